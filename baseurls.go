@@ -0,0 +1,91 @@
+package goinsta
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+)
+
+// BaseURLs are the URLs requests are sent to. They can be changed with
+// Instagram.SetBaseURLs, e.g. to route traffic through a gateway or to a local
+// stub. Empty fields default to the values of DefaultBaseURLs.
+type BaseURLs struct {
+	// Base is used for requests outside of the API, e.g. https://i.instagram.com/
+	Base string `json:"base"`
+
+	// API is used for most requests, e.g. https://i.instagram.com/api/v1/
+	API string `json:"api"`
+	// APIb is used for requests sent to the b.i.instagram.com host
+	APIb string `json:"api_b"`
+
+	// APIv2 and APIv2b are the v2 counterparts of API and APIb
+	APIv2  string `json:"api_v2"`
+	APIv2b string `json:"api_v2_b"`
+
+	// Upload is the rupload host photos and videos are uploaded to
+	Upload string `json:"upload"`
+}
+
+// DefaultBaseURLs returns the Instagram URLs used by default.
+func DefaultBaseURLs() BaseURLs {
+	return BaseURLs{
+		Base:   baseUrl,
+		API:    instaAPIUrl,
+		APIb:   instaAPIUrlb,
+		APIv2:  instaAPIUrlv2,
+		APIv2b: instaAPIUrlv2b,
+		Upload: ruploadUrl,
+	}
+}
+
+// withDefaults fills in empty fields with the default URLs.
+func (u BaseURLs) withDefaults() BaseURLs {
+	d := DefaultBaseURLs()
+	for _, f := range []struct{ v, d *string }{
+		{&u.Base, &d.Base},
+		{&u.API, &d.API},
+		{&u.APIb, &d.APIb},
+		{&u.APIv2, &d.APIv2},
+		{&u.APIv2b, &d.APIv2b},
+		{&u.Upload, &d.Upload},
+	} {
+		if *f.v == "" {
+			*f.v = *f.d
+		}
+	}
+	return u
+}
+
+// validate checks that all URLs are absolute, and makes sure they end with a
+// slash, as endpoints are appended to them.
+func (u *BaseURLs) validate() error {
+	for _, v := range []*string{&u.Base, &u.API, &u.APIb, &u.APIv2, &u.APIv2b, &u.Upload} {
+		parsed, err := neturl.Parse(*v)
+		if err != nil {
+			return err
+		}
+		if parsed.Scheme == "" || parsed.Host == "" {
+			return fmt.Errorf("base url %q is not absolute", *v)
+		}
+		if !strings.HasSuffix(*v, "/") {
+			*v += "/"
+		}
+	}
+	return nil
+}
+
+// SetBaseURLs sets the URLs requests are sent to. Empty fields are set to their
+// default value. The URLs are persisted when the config is exported.
+func (insta *Instagram) SetBaseURLs(urls BaseURLs) error {
+	urls = urls.withDefaults()
+	if err := urls.validate(); err != nil {
+		return err
+	}
+	insta.baseURLs = urls
+	return nil
+}
+
+// BaseURLs returns the URLs requests are sent to.
+func (insta *Instagram) BaseURLs() BaseURLs {
+	return insta.baseURLs
+}
@@ -0,0 +1,105 @@
+// Package boltstore implements a goinsta.SessionStore backed by a bbolt
+// database, to keep many sessions in a single file.
+//
+//	store, err := boltstore.Open("sessions.db")
+//	if err != nil {
+//		return err
+//	}
+//	defer store.Close()
+//
+//	insta, err := goinsta.LoadSession(store, "username")
+package boltstore
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("sessions")
+
+// Store is a goinsta.SessionStore, that saves sessions in a bbolt database.
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens, or creates, the database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	s, err := New(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// New creates a store using an already opened database. Sessions are kept in
+// the sessions bucket.
+func New(db *bolt.DB) (*Store, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Load returns the session saved for username.
+func (s *Store) Load(username string) (*goinsta.ConfigFile, error) {
+	var config *goinsta.ConfigFile
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket).Get([]byte(username))
+		if b == nil {
+			return goinsta.ErrSessionNotFound
+		}
+		config = &goinsta.ConfigFile{}
+		return json.Unmarshal(b, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Save saves a session.
+func (s *Store) Save(config goinsta.ConfigFile) error {
+	b, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(config.User), b)
+	})
+}
+
+// List returns the usernames of all sessions saved, sorted.
+func (s *Store) List() ([]string, error) {
+	var users []string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, _ []byte) error {
+			users = append(users, string(k))
+			return nil
+		})
+	})
+	return users, err
+}
+
+// Delete deletes the session saved for username.
+func (s *Store) Delete(username string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(username))
+	})
+}
@@ -1,7 +1,9 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 )
 
@@ -31,6 +33,9 @@ type Challenge struct {
 	URL               string            `json:"url"`
 	ApiPath           string            `json:"api_path"`
 	Context           *ChallengeContext `json:"challenge_context"`
+	StepName          string            `json:"step_name"`
+	StepData          ChallengeStepData `json:"step_data"`
+	Action            string            `json:"action"`
 	FlowRenderType    int               `json:"flow_render_type"`
 	HideWebviewHeader bool              `json:"hide_webview_header"`
 	Lock              bool              `json:"lock"`
@@ -61,6 +66,17 @@ type ChallengeContext struct {
 	UserID      int64             `json:"user_id"`
 }
 
+// UnmarshalJSON decodes the challenge context, which Instagram sometimes
+// sends as an object, and sometimes as a string containing JSON.
+func (c *ChallengeContext) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err == nil {
+		b = []byte(str)
+	}
+	type plain ChallengeContext
+	return json.Unmarshal(b, (*plain)(c))
+}
+
 type challengeResp struct {
 	*Challenge
 }
@@ -71,11 +87,77 @@ func newChallenge(insta *Instagram) *Challenge {
 	}
 }
 
+// Step returns the name of the current step of the challenge, e.g.
+// select_verify_method or verify_email.
+func (c *Challenge) Step() string {
+	if c.StepName != "" {
+		return c.StepName
+	}
+	if c.Context != nil {
+		return c.Context.StepName
+	}
+	return ""
+}
+
+// suggestedChoice returns the verification method Instagram suggests.
+func (c *Challenge) suggestedChoice() string {
+	if c.StepData.Choice != "" {
+		return c.StepData.Choice
+	}
+	if c.Context != nil {
+		return c.Context.StepData.Choice
+	}
+	return ""
+}
+
+// CodeRequired reports whether a security code has been sent, that needs to
+// be submitted with SendSecurityCode.
+func (c *Challenge) CodeRequired() bool {
+	switch c.Step() {
+	case "verify_code", "verify_email", "verify_sms":
+		return true
+	}
+	return false
+}
+
+// userID returns the ID of the account the challenge is for, which is not
+// logged in yet if the challenge was encountered while logging in.
+func (c *Challenge) userID() int64 {
+	switch {
+	case c.insta.Account != nil:
+		return c.insta.Account.ID
+	case c.UserID != 0:
+		return c.UserID
+	case c.Context != nil:
+		return c.Context.UserID
+	}
+	return 0
+}
+
+// update replaces the challenge with the one in a challenge API response.
+func (c *Challenge) update(body []byte) error {
+	insta := c.insta
+	resp := challengeResp{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return err
+	}
+	if resp.Challenge == nil {
+		return nil
+	}
+	*c = *resp.Challenge
+	c.insta = insta
+	if c.LoggedInUser != nil {
+		c.LoggedInUser.insta = insta
+		insta.Account = c.LoggedInUser
+	}
+	return nil
+}
+
 // updateState updates current data from challenge url
-func (c *Challenge) updateState() error {
+func (c *Challenge) updateState(ctx context.Context) error {
 	insta := c.insta
 
-	ctx, err := json.Marshal(c.Context)
+	challengeCtx, err := json.Marshal(c.Context)
 	if err != nil {
 		return err
 	}
@@ -86,23 +168,19 @@ func (c *Challenge) updateState() error {
 			Query: map[string]string{
 				"guid":              insta.uuid,
 				"device_id":         insta.dID,
-				"challenge_context": string(ctx),
+				"challenge_context": string(challengeCtx),
 			},
+			Context: ctx,
 		},
 	)
-	if err == nil {
-		resp := challengeResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			*c = *resp.Challenge
-			c.insta = insta
-		}
+	if err != nil {
+		return err
 	}
-	return err
+	return c.update(body)
 }
 
 // selectVerifyMethod selects a way and verify it (Phone number = 0, email = 1)
-func (challenge *Challenge) selectVerifyMethod(choice string, isReplay ...bool) error {
+func (challenge *Challenge) selectVerifyMethod(ctx context.Context, choice string, isReplay ...bool) error {
 	insta := challenge.insta
 
 	url := challenge.insta.challengeURL
@@ -116,7 +194,7 @@ func (challenge *Challenge) selectVerifyMethod(choice string, isReplay ...bool)
 			"guid":      insta.uuid,
 			"device_id": insta.dID,
 			"_uuid":     insta.uuid,
-			"_uid":      toString(insta.Account.ID),
+			"_uid":      toString(challenge.userID()),
 		})
 	if err != nil {
 		return err
@@ -127,21 +205,23 @@ func (challenge *Challenge) selectVerifyMethod(choice string, isReplay ...bool)
 			Endpoint: url,
 			Query:    generateSignature(data),
 			IsPost:   true,
+			Context:  ctx,
 		},
 	)
-	if err == nil {
-		resp := challengeResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			*challenge = *resp.Challenge
-			challenge.insta = insta
-		}
+	if err != nil {
+		return err
 	}
-	return err
+	return challenge.update(body)
 }
 
-// sendSecurityCode sends the code received in the message
+// SendSecurityCode sends the code received in the message
 func (challenge *Challenge) SendSecurityCode(code string) error {
+	return challenge.SendSecurityCodeContext(challenge.insta.Context(), code)
+}
+
+// SendSecurityCodeContext sends the code received in the message, with ctx
+// to cancel the request.
+func (challenge *Challenge) SendSecurityCodeContext(ctx context.Context, code string) error {
 	insta := challenge.insta
 	url := challenge.insta.challengeURL
 
@@ -150,7 +230,7 @@ func (challenge *Challenge) SendSecurityCode(code string) error {
 		"guid":          insta.uuid,
 		"device_id":     insta.dID,
 		"_uuid":         insta.uuid,
-		"_uid":          toString(insta.Account.ID),
+		"_uid":          toString(challenge.userID()),
 	})
 	if err != nil {
 		return err
@@ -161,69 +241,82 @@ func (challenge *Challenge) SendSecurityCode(code string) error {
 			Endpoint: url,
 			IsPost:   true,
 			Query:    generateSignature(data),
+			Context:  ctx,
 		},
 	)
-	if err == nil {
-		resp := challengeResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			*challenge = *resp.Challenge
-			challenge.insta = insta
-			challenge.LoggedInUser.insta = insta
-			insta.Account = challenge.LoggedInUser
-		}
+	if err != nil {
+		return err
 	}
-	return err
+	return challenge.update(body)
 }
 
 // deltaLoginReview process with choice (It was me = 0, It wasn't me = 1)
-func (c *Challenge) deltaLoginReview() error {
-	return c.selectVerifyMethod("0")
+func (c *Challenge) deltaLoginReview(ctx context.Context) error {
+	return c.selectVerifyMethod(ctx, "0")
 }
 
 func (c *Challenge) ProcessOld(apiURL string) error {
 	c.insta.challengeURL = apiURL[1:]
+	ctx := c.insta.Context()
 
-	if err := c.updateState(); err != nil {
+	if err := c.updateState(ctx); err != nil {
 		return err
 	}
 
-	switch c.Context.StepName {
+	switch c.Step() {
 	case "select_verify_method":
-		return c.selectVerifyMethod(c.Context.StepData.Choice)
+		return c.selectVerifyMethod(ctx, c.suggestedChoice())
 	case "delta_login_review":
-		return c.deltaLoginReview()
+		return c.deltaLoginReview(ctx)
 	}
 
-	return ErrChallengeProcess{StepName: c.Context.StepName}
+	return ErrChallengeProcess{StepName: c.Step()}
 }
 
-// Process will open up the challenge url in a chromium browser and
-//   take a screenshot. Please report the screenshot and printed out struct so challenge
-//   automation can be build in.
+// Process solves the challenge with the solver set with
+// Instagram.SetChallengeSolver.
 func (c *Challenge) Process() error {
+	return c.ProcessContext(c.insta.Context())
+}
+
+// ProcessContext solves the challenge with the solver set with
+// Instagram.SetChallengeSolver. The solver selects a verification method, and
+// if Instagram sent a security code, the code is received and submitted.
+func (c *Challenge) ProcessContext(ctx context.Context) error {
 	insta := c.insta
+	if c.ApiPath != "" {
+		insta.challengeURL = strings.TrimPrefix(c.ApiPath, "/")
+	}
 
-	insta.warnHandler("Encountered a captcha challenge, goinsta will attempt to open the challenge in a headless chromium browser, and take a screenshot. Please report the details in a github issue.")
-	err := insta.openChallenge(c.URL)
-	err = checkHeadlessErr(err)
+	solver := insta.solver()
+	codeSent, err := solver.SelectMethod(ctx, c)
+	if err != nil || !codeSent {
+		return err
+	}
 
-	return err
+	code, err := solver.ReceiveCode(ctx, c)
+	if err != nil {
+		return err
+	}
+	return solver.SubmitCode(ctx, c, code)
 }
 
-// Process will open up the url passed as a checkpoint response (not a challenge)
-//   in a headless browser. This method is experimental, please report if you still
-//   get a /privacy/checks/ checkpoint error.
+// Process accepts the cookies the checkpoint prompts for, with the solver set
+// with Instagram.SetChallengeSolver.
 func (c *Checkpoint) Process() error {
+	return c.ProcessContext(c.insta.Context())
+}
+
+// ProcessContext accepts the cookies the checkpoint prompts for, with the
+// solver set with Instagram.SetChallengeSolver.
+func (c *Checkpoint) ProcessContext(ctx context.Context) error {
 	insta := c.insta
 	if insta.privacyRequested.Get() {
-		panic("Privacy request again, it hus failed, panicing")
+		return errors.New("accepting the privacy cookies has failed, checkpoint was encountered again")
 	}
 
 	insta.privacyRequested.Set(true)
-	err := insta.acceptPrivacyCookies(c.URL)
-	err = checkHeadlessErr(err)
-	if err != nil {
+	if err := insta.solver().AcceptCookies(ctx, c); err != nil {
 		return err
 	}
 
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"iter"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// manifest is stored in archive.json, at the root of the archive.
+type manifest struct {
+	Account  *goinsta.Account   `json:"account"`
+	Updated  time.Time          `json:"updated"`
+	Sections map[string]section `json:"sections"`
+}
+
+// section summarizes the last export of a part of the archive.
+type section struct {
+	Items   int       `json:"items"`
+	New     int       `json:"new"`
+	Updated time.Time `json:"updated"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// mediaSection is stored in <section>/items.json, with the media files of
+// the items in <section>/media.
+type mediaSection struct {
+	Title string          `json:"title,omitempty"`
+	Items []*goinsta.Item `json:"items"`
+
+	// Downloaded holds the IDs of the items whose media has been saved
+	Downloaded map[string]bool `json:"downloaded"`
+}
+
+// thread is stored in inbox/<thread id>.json.
+type thread struct {
+	ID             string               `json:"thread_id"`
+	Title          string               `json:"thread_title"`
+	Users          []*goinsta.User      `json:"users"`
+	LastActivityAt int64                `json:"last_activity_at"`
+	Messages       []*goinsta.InboxItem `json:"messages"`
+}
+
+// threadIndex is stored per conversation in inbox/index.json, to tell which
+// conversations have new messages.
+type threadIndex struct {
+	LastActivityAt int64 `json:"last_activity_at"`
+	Messages       int   `json:"messages"`
+}
+
+type archiver struct {
+	insta *goinsta.Instagram
+	dir   string
+	full  bool
+	media bool
+
+	manifest manifest
+}
+
+// run exports all sections, and reports whether any of them failed. A failed
+// section doesn't stop the others from being exported.
+func (a *archiver) run() (failed bool) {
+	if err := a.load("archive.json", &a.manifest); err != nil {
+		log.Printf("Failed to load manifest, starting over: %v", err)
+	}
+	if a.manifest.Sections == nil {
+		a.manifest.Sections = map[string]section{}
+	}
+
+	account := a.insta.Account
+	steps := []struct {
+		name string
+		run  func() (total, added int, err error)
+	}{
+		{"feed", func() (int, int, error) {
+			it := account.Feed().Iter()
+			return a.mediaFeed("feed", "", it.All(), it.Error)
+		}},
+		{"archived", func() (int, int, error) {
+			it := account.Archived().Iter()
+			return a.mediaFeed("archived", "", it.All(), it.Error)
+		}},
+		{"saved", func() (int, int, error) {
+			it := account.Saved().Iter()
+			return a.mediaFeed("saved", "", it.All(), it.Error)
+		}},
+		{"liked", func() (int, int, error) {
+			it := account.Liked().Iter()
+			return a.mediaFeed("liked", "", it.All(), it.Error)
+		}},
+		{"collections", a.collections},
+		{"highlights", a.highlights},
+		{"followers", func() (int, int, error) {
+			return a.users("followers.json", account.Followers(""))
+		}},
+		{"following", func() (int, int, error) {
+			return a.users("following.json", account.Following("", goinsta.DefaultOrder))
+		}},
+		{"inbox", a.inbox},
+	}
+
+	for _, step := range steps {
+		log.Printf("Exporting %s", step.name)
+		total, added, err := step.run()
+		s := section{Items: total, New: added, Updated: time.Now()}
+		if err != nil {
+			log.Printf("Failed to export %s: %v", step.name, err)
+			s = a.manifest.Sections[step.name]
+			s.Error = err.Error()
+			failed = true
+		} else {
+			log.Printf("Exported %s: %d items, %d new", step.name, total, added)
+		}
+		a.manifest.Sections[step.name] = s
+	}
+
+	a.manifest.Account = account
+	a.manifest.Updated = time.Now()
+	if err := a.save("archive.json", a.manifest); err != nil {
+		log.Printf("Failed to save manifest: %v", err)
+		failed = true
+	}
+	return failed
+}
+
+// mediaFeed archives the posts of a feed into dir. Feeds list the newest
+// posts first, so unless a.full is set, the walk stops at the first post that
+// has already been archived.
+//
+// New posts are only added to the archive if the whole walk succeeds, as a
+// later run would otherwise stop at them, and skip the posts in between.
+// Their media is kept, and not downloaded again.
+func (a *archiver) mediaFeed(dir, title string, items iter.Seq[*goinsta.Item], errf func() error) (int, int, error) {
+	var s mediaSection
+	if err := a.load(filepath.Join(dir, "items.json"), &s); err != nil {
+		return 0, 0, err
+	}
+	if s.Downloaded == nil {
+		s.Downloaded = map[string]bool{}
+	}
+	if title != "" {
+		s.Title = title
+	}
+
+	known := map[string]bool{}
+	for _, item := range s.Items {
+		known[item.GetID()] = true
+	}
+
+	var added []*goinsta.Item
+	for item := range items {
+		id := item.GetID()
+		if known[id] {
+			if !a.full {
+				break
+			}
+		} else {
+			known[id] = true
+			added = append(added, item)
+		}
+		a.download(dir, item, &s)
+	}
+
+	if err := errf(); err != nil {
+		if saveErr := a.save(filepath.Join(dir, "items.json"), &s); saveErr != nil {
+			log.Printf("Failed to save %s: %v", dir, saveErr)
+		}
+		return 0, 0, err
+	}
+
+	s.Items = append(added, s.Items...)
+	return len(s.Items), len(added), a.save(filepath.Join(dir, "items.json"), &s)
+}
+
+// download saves the media of item into dir/media, if it hasn't been yet.
+// Failed downloads are logged, and retried by a run with -full.
+func (a *archiver) download(dir string, item *goinsta.Item, s *mediaSection) {
+	id := item.GetID()
+	if !a.media || s.Downloaded[id] {
+		return
+	}
+	if err := item.DownloadTo(filepath.Join(a.dir, dir, "media", id)); err != nil {
+		log.Printf("Failed to download %s: %v", id, err)
+		return
+	}
+	s.Downloaded[id] = true
+}
+
+// collections archives the posts of each collection into
+// collections/<collection id>. Only collections of posts are archived, the
+// "All Posts" collection is exported as saved.
+func (a *archiver) collections() (int, int, error) {
+	c := a.insta.Collections
+	for c.Next() {
+	}
+	if err := c.Error(); err != nil && !errors.Is(err, goinsta.ErrNoMore) {
+		return 0, 0, err
+	}
+
+	var total, added int
+	var errs []error
+	for _, col := range c.Items {
+		if col.Type != "MEDIA" {
+			continue
+		}
+		it := col.Iter()
+		n, m, err := a.mediaFeed(filepath.Join("collections", col.ID), col.Name, it.All(), it.Error)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		total += n
+		added += m
+	}
+	return total, added, errors.Join(errs...)
+}
+
+// highlights archives the stories of each highlight of the account into
+// highlights/<highlight id>.
+func (a *archiver) highlights() (int, int, error) {
+	user, err := a.insta.Profiles.ByID(a.insta.Account.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	reels, err := user.Highlights()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total, added int
+	var errs []error
+	for _, reel := range reels {
+		if len(reel.Items) == 0 {
+			if err := reel.Sync(); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		id, _ := reel.ID.(string)
+		dir := filepath.Join("highlights", strings.TrimPrefix(id, "highlight:"))
+
+		// Highlights list the oldest stories first
+		items := slices.Clone(reel.Items)
+		slices.Reverse(items)
+		n, m, err := a.mediaFeed(dir, reel.Title, slices.Values(items), func() error { return nil })
+		if err != nil {
+			errs = append(errs, err)
+		}
+		total += n
+		added += m
+	}
+	return total, added, errors.Join(errs...)
+}
+
+// users archives a user list in full.
+func (a *archiver) users(file string, users *goinsta.Users) (int, int, error) {
+	var old []*goinsta.User
+	if err := a.load(file, &old); err != nil {
+		return 0, 0, err
+	}
+	known := map[int64]bool{}
+	for _, u := range old {
+		known[u.ID] = true
+	}
+
+	list, err := users.Iter().Collect()
+	if err != nil {
+		return 0, 0, err
+	}
+	var added int
+	for _, u := range list {
+		if !known[u.ID] {
+			added++
+		}
+	}
+	return len(list), added, a.save(file, list)
+}
+
+// inbox archives the messages of each conversation into
+// inbox/<thread id>.json. The inbox lists the most recently active
+// conversations first, so unless a.full is set, the walk stops at the first
+// conversation without new messages.
+func (a *archiver) inbox() (int, int, error) {
+	index := map[string]threadIndex{}
+	if err := a.load(filepath.Join("inbox", "index.json"), &index); err != nil {
+		return 0, 0, err
+	}
+
+	// The index is only updated once all conversations have been archived.
+	// Otherwise the next run stops at a conversation archived after one that
+	// failed, and never retries the failed one, like mediaFeed.
+	var added int
+	var errs []error
+	updated := map[string]threadIndex{}
+	it := a.insta.Inbox.Iter()
+	for c := range it.All() {
+		prev, ok := index[c.ID]
+		if ok && prev.LastActivityAt == c.LastActivityAt && !a.full {
+			break
+		}
+		n, m, err := a.conversation(c)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		updated[c.ID] = threadIndex{LastActivityAt: c.LastActivityAt, Messages: n}
+		added += m
+	}
+	if err := it.Error(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return 0, 0, errors.Join(errs...)
+	}
+
+	for id, t := range updated {
+		index[id] = t
+	}
+	if err := a.save(filepath.Join("inbox", "index.json"), index); err != nil {
+		return 0, 0, err
+	}
+
+	var total int
+	for _, t := range index {
+		total += t.Messages
+	}
+	return total, added, nil
+}
+
+// conversation archives the messages of a conversation, newest first, like
+// mediaFeed does with posts.
+func (a *archiver) conversation(c *goinsta.Conversation) (int, int, error) {
+	file := filepath.Join("inbox", c.ID+".json")
+	var t thread
+	if err := a.load(file, &t); err != nil {
+		return 0, 0, err
+	}
+	known := map[string]bool{}
+	for _, msg := range t.Messages {
+		known[msg.ID] = true
+	}
+
+	var added []*goinsta.InboxItem
+	it := c.Iter()
+	for msg := range it.All() {
+		if known[msg.ID] {
+			if !a.full {
+				break
+			}
+			continue
+		}
+		known[msg.ID] = true
+		added = append(added, msg)
+	}
+	if err := it.Error(); err != nil {
+		return 0, 0, err
+	}
+
+	t.ID = c.ID
+	t.Title = c.Title
+	t.Users = c.Users
+	t.LastActivityAt = c.LastActivityAt
+	t.Messages = append(added, t.Messages...)
+	return len(t.Messages), len(added), a.save(file, &t)
+}
+
+// load decodes file from the archive into v. A file that doesn't exist yet
+// leaves v untouched.
+func (a *archiver) load(file string, v interface{}) error {
+	b, err := os.ReadFile(filepath.Join(a.dir, file))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// save encodes v into file in the archive. The file is replaced at once, so
+// an interrupted run doesn't leave it half written.
+func (a *archiver) save(file string, v interface{}) error {
+	path := filepath.Join(a.dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
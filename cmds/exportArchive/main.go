@@ -0,0 +1,59 @@
+// Use this program to export the data of an account into a self-contained
+// archive, like the data download Instagram offers. It exports the posts,
+// archived posts, saved posts and collections, liked posts, highlights,
+// followers, following, and direct messages, as JSON metadata alongside the
+// media files of the posts.
+//
+// Usage:
+//
+//	go run . -session ~/.goinsta -out ./archive
+//
+// The session is a config exported with Instagram.Export. Re-running the
+// program on the same archive only fetches the items that have been added
+// since, unless -full is passed. Followers and following are exported in full
+// every time, as people unfollow as well.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+func main() {
+	session := flag.String("session", "", "path to a session exported with Instagram.Export")
+	out := flag.String("out", "archive", "directory to write the archive to")
+	full := flag.Bool("full", false, "walk all items again instead of stopping at the first archived item, e.g. to retry failed downloads")
+	noMedia := flag.Bool("no-media", false, "only export metadata, without downloading media")
+	flag.Parse()
+
+	if *session == "" {
+		fmt.Fprintln(os.Stderr, "Please provide a session with -session")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	insta, err := goinsta.Import(*session)
+	if err != nil {
+		log.Fatalf("Failed to import session: %v", err)
+	}
+
+	a := &archiver{
+		insta: insta,
+		dir:   *out,
+		full:  *full,
+		media: !*noMedia,
+	}
+	failed := a.run()
+
+	// Keep the refreshed cookies for the next run
+	if err := insta.Export(*session); err != nil {
+		log.Printf("Failed to save session: %v", err)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,66 @@
+package goinsta
+
+import (
+	"context"
+)
+
+// CodeMethod is how Instagram delivered a verification code.
+type CodeMethod string
+
+const (
+	CodeSMS      CodeMethod = "sms"
+	CodeWhatsApp CodeMethod = "whatsapp"
+	CodeEmail    CodeMethod = "email"
+	// CodeTOTP codes are generated by an authenticator app
+	CodeTOTP CodeMethod = "totp"
+)
+
+// CodeReason is why a verification code is needed.
+type CodeReason string
+
+const (
+	// CodeForTwoFactor codes are needed to login with two factor
+	// authentication
+	CodeForTwoFactor CodeReason = "two_factor"
+	// CodeForChallenge codes are needed to solve a challenge
+	CodeForChallenge CodeReason = "challenge"
+)
+
+// CodeRequest describes a verification code that is needed, see CodeProvider.
+type CodeRequest struct {
+	Username string
+	Reason   CodeReason
+	Method   CodeMethod
+
+	// Destination is the masked phone number or email address the code has
+	// been sent to, e.g. "+1 *** ***-**-00". Empty for TOTP codes.
+	Destination string
+}
+
+// CodeProvider returns the verification code described by req, e.g. by
+// prompting a human for it. It is called while the request that needs the
+// code is pending, which is resumed once the code has been submitted. Return
+// an error to give up, or honour the context to time out.
+type CodeProvider func(ctx context.Context, req CodeRequest) (string, error)
+
+// SetCodeProvider sets the callback used to get the verification codes
+// Instagram sends by SMS, WhatsApp or email, when logging in with two factor
+// authentication, or solving a challenge. If a TOTP seed has been set with
+// SetTOTPSeed, two factor codes are generated instead.
+func (insta *Instagram) SetCodeProvider(p CodeProvider) {
+	insta.codeProvider = p
+}
+
+// requestCode asks the code provider for a code, or returns errNoProvider if
+// no provider has been set.
+func (insta *Instagram) requestCode(ctx context.Context, req CodeRequest, errNoProvider error) (string, error) {
+	if insta.codeProvider == nil {
+		return "", errNoProvider
+	}
+	insta.log().Info("Requesting verification code",
+		"reason", req.Reason,
+		"method", req.Method,
+		"destination", req.Destination,
+	)
+	return insta.codeProvider(ctx, req)
+}
@@ -465,7 +465,7 @@ func (c *Collection) Next(params ...interface{}) bool {
 		c.err = c.all.err
 		return r
 	}
-	if len(c.Items) == 0 {
+	if len(c.Items) == 0 && c.GetNextID() == "" {
 		if err := c.Sync(); err != nil {
 			c.err = err
 			return false
@@ -545,7 +545,7 @@ func (item *Item) SaveTo(c *Collection) error {
 		if errIsFatal(err) {
 			return err
 		}
-		insta.warnHandler(errors.New("non fatal error, failed to save post to all"))
+		insta.log().Warn("Non fatal error, failed to save post to all", "error", err)
 	}
 
 	data, err := json.Marshal(
@@ -655,7 +655,7 @@ func (media *SavedMedia) Next(params ...interface{}) bool {
 	if media.err != nil {
 		return false
 	}
-	if len(media.Items) == 0 {
+	if len(media.Items) == 0 && media.GetNextID() == "" {
 		if err := media.Sync(); err != nil {
 			media.err = err
 			return false
@@ -0,0 +1,49 @@
+package goinsta
+
+import (
+	"fmt"
+)
+
+// configMigrations upgrade a ConfigFile from one schema version to the next;
+// configMigrations[i] upgrades a config of version i to version i+1.
+//
+// When adding or changing a ConfigFile field in a way that older exports would
+// import incorrectly, append a migration filling in the field, and increment
+// ConfigVersion.
+var configMigrations = []func(config *ConfigFile) error{
+	migrateConfigV0,
+}
+
+// ConfigVersion is the schema version of the ConfigFile exported by this
+// version of goinsta. It must equal len(configMigrations).
+const ConfigVersion = 1
+
+// migrateConfig upgrades config to ConfigVersion. Configs written by a newer
+// version of goinsta are rejected with ErrConfigVersion, as they may contain
+// data that would be lost.
+func migrateConfig(config *ConfigFile) error {
+	if config.Version > ConfigVersion {
+		return fmt.Errorf("%w: config has version %d, this version of goinsta supports up to version %d",
+			ErrConfigVersion, config.Version, ConfigVersion)
+	}
+	for config.Version < ConfigVersion {
+		if err := configMigrations[config.Version](config); err != nil {
+			return fmt.Errorf("failed to migrate config from version %d: %w", config.Version, err)
+		}
+		config.Version++
+	}
+	return nil
+}
+
+// migrateConfigV0 upgrades configs exported before they were versioned. Fields
+// added over time are missing from older exports, fill them in with the values
+// New would have used.
+func migrateConfigV0(config *ConfigFile) error {
+	if config.FamilyID == "" {
+		config.FamilyID = generateUUID()
+	}
+	if config.Device.Model == "" {
+		config.Device = GalaxyS10
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ const (
 	instaAPIUrlb   = "https://b.i.instagram.com/api/v1/"
 	instaAPIUrlv2  = "https://i.instagram.com/api/v2/"
 	instaAPIUrlv2b = "https://b.i.instagram.com/api/v2/"
+	ruploadUrl     = "https://i.instagram.com/"
 
 	// header values
 	bloksVerID         = "927f06374b80864ae6a0b04757048065714dc50ff15d2b8b3de8d0b6de961649"
@@ -261,9 +262,75 @@ const (
 	// 2FA
 	url2FACheckTrusted = "two_factor/check_trusted_notification_status/"
 	url2FALogin        = "accounts/two_factor_login/"
+	url2FAGenerateTOTP = "accounts/generate_two_factor_totp_key/"
+	url2FAEnableTOTP   = "accounts/enable_totp_two_factor/"
+	url2FADisableTOTP  = "accounts/disable_totp_two_factor/"
+	url2FABackupCodes  = "accounts/regen_backup_codes/"
+	urlSecurityInfo    = "accounts/account_security_info/"
 )
 
-// Errors
+// Endpoint templates containing format vars, to report endpoints by template
+// rather than by URL, see EndpointTemplate.
+var endpointTemplates = []string{
+	urlCollectionEdit,
+	urlCollectionDelete,
+	urlCollectionFeedAll,
+	urlCollectionFeedPosts,
+	urlFollowers,
+	urlFollowing,
+	urlUserByName,
+	urlUserByID,
+	urlUserBlock,
+	urlUserUnblock,
+	urlUserFollow,
+	urlUserUnfollow,
+	urlUserFeed,
+	urlFriendship,
+	urlFriendshipApprove,
+	urlFriendshipIgnore,
+	urlUserStories,
+	urlUserTags,
+	urlUserInfo,
+	urlUserHighlights,
+	urlFeedLocationID,
+	urlFeedLocations,
+	urlFeedTag,
+	urlMediaInfo,
+	urlMediaDelete,
+	urlMediaLike,
+	urlMediaUnlike,
+	urlMediaSave,
+	urlMediaUnsave,
+	urlMediaLikers,
+	urlLiveInfo,
+	urlLiveComments,
+	urlLiveLikeCount,
+	urlLiveHeartbeat,
+	urlIGTVSeries,
+	urlCommentAdd,
+	urlCommentDelete,
+	urlCommentBulkDelete,
+	urlCommentSync,
+	urlCommentDisable,
+	urlCommentEnable,
+	urlCommentLike,
+	urlCommentUnlike,
+	urlInboxThread,
+	urlInboxMute,
+	urlInboxUnmute,
+	urlInboxGetItems,
+	urlInboxMsgSeen,
+	urlInboxApprove,
+	urlInboxHide,
+	urlTagInfo,
+	urlTagStories,
+	urlTagContent,
+	urlUploadPhoto,
+	urlUploadVideo,
+}
+
+// Errors. Requests return typed errors wrapping these, so compare with
+// errors.Is rather than ==, see APIError.
 var (
 	RespErr2FA = "two_factor_required"
 
@@ -275,13 +342,17 @@ var (
 	ErrSessionNotSet   = errors.New("session identifier is not set, please log in again to set it")
 	ErrLogoutFailed    = errors.New("failed to logout")
 
+	ErrFeedbackRequired = errors.New("action blocked by instagram, please wait before you try again")
+	ErrNotFound         = errors.New("not found")
+
 	ErrChallengeRequired  = errors.New("challenge required")
 	ErrCheckpointRequired = errors.New("checkpoint required")
 	ErrCheckpointPassed   = errors.New("a checkpoint was thrown, but goinsta managed to solve it. Please call the function again")
 	ErrChallengeFailed    = errors.New("failed to solve challenge automatically")
+	ErrChallengeNoCode    = errors.New("a security code was sent to solve the challenge, but no code provider has been set. Please call SetCodeProvider")
 
 	Err2FARequired = errors.New("two Factor Autentication required. Please call insta.TwoFactorInfo.Login2FA(code)")
-	Err2FANoCode   = errors.New("2FA seed is not set, and no code was provided. Please do atleast one of them")
+	Err2FANoCode   = errors.New("2FA seed is not set, no code provider is set, and no code was provided. Please do atleast one of them")
 	ErrInvalidCode = errors.New("the security code provided is incorrect")
 
 	// Upload Errors
@@ -305,6 +376,7 @@ var (
 	ErrNoMore       = errors.New("no more posts availible, page end has been reached")
 	ErrNotHighlight = errors.New("unable to sync, Reel is not of type highlight")
 	ErrMediaDeleted = errors.New("sorry, this media has been deleted")
+	ErrCursorKind   = errors.New("unable to resume, cursor belongs to another type of feed")
 
 	// Inbox
 	ErrConvNotPending = errors.New("unable to perform action, conversation is not pending")
@@ -323,4 +395,11 @@ var (
 
 	// Headless
 	ErrChromeNotFound = errors.New("to solve challenges a (headless) Chrome browser is used, but none was found. Please install Chromium or Google Chrome, and try again")
+
+	// Encrypted sessions
+	ErrSessionEncrypted = errors.New("session is encrypted, please import it with a key")
+	ErrWrongSessionKey  = errors.New("unable to decrypt session, wrong key or corrupted data")
+
+	// Configs
+	ErrConfigVersion = errors.New("config was exported by a newer version of goinsta, please upgrade")
 )
@@ -0,0 +1,159 @@
+package goinsta
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Action is a type of action Instagram may temporarily block an account from
+// performing, see FeedbackRequiredError.
+type Action string
+
+const (
+	ActionFollow  Action = "follow"
+	ActionLike    Action = "like"
+	ActionComment Action = "comment"
+	ActionMessage Action = "message"
+)
+
+// DefaultActionCooldown is how long an action is considered blocked after
+// Instagram responded with feedback_required. Instagram doesn't say when a
+// block will be lifted, it usually takes a few hours to days.
+const DefaultActionCooldown = 24 * time.Hour
+
+// CooldownError is returned if an action is not performed, because Instagram
+// blocked it earlier, and the cooldown has not yet expired. It wraps
+// ErrFeedbackRequired.
+type CooldownError struct {
+	Action Action
+	Until  time.Time
+}
+
+func (e CooldownError) Error() string {
+	return fmt.Sprintf("%s has been blocked by instagram, cooling down until %s",
+		e.Action, e.Until.Format(time.RFC3339))
+}
+
+func (e CooldownError) Unwrap() error {
+	return ErrFeedbackRequired
+}
+
+// RetryHint reports the action should not be retried before the cooldown
+// expires.
+func (e CooldownError) RetryHint() (bool, time.Duration) {
+	return false, time.Until(e.Until)
+}
+
+// SetActionCooldown sets how long an action is considered blocked after
+// Instagram responded with feedback_required. Defaults to
+// DefaultActionCooldown.
+func (insta *Instagram) SetActionCooldown(d time.Duration) {
+	insta.cooldownMu.Lock()
+	defer insta.cooldownMu.Unlock()
+	insta.cooldownDuration = d
+}
+
+// Cooldown returns the time until which action is blocked, or the zero time
+// if it isn't.
+func (insta *Instagram) Cooldown(action Action) time.Time {
+	insta.cooldownMu.RLock()
+	defer insta.cooldownMu.RUnlock()
+
+	until := insta.cooldowns[action]
+	if time.Now().After(until) {
+		return time.Time{}
+	}
+	return until
+}
+
+// SetCooldown blocks action until the time provided. Pass the zero time to
+// lift a block.
+func (insta *Instagram) SetCooldown(action Action, until time.Time) {
+	insta.cooldownMu.Lock()
+	defer insta.cooldownMu.Unlock()
+
+	if until.IsZero() {
+		delete(insta.cooldowns, action)
+		return
+	}
+	if insta.cooldowns == nil {
+		insta.cooldowns = map[Action]time.Time{}
+	}
+	insta.cooldowns[action] = until
+}
+
+// latestCooldown returns the time the last blocked action is unblocked. If no
+// cooldown has been recorded, e.g. because the request was not made for a
+// known action, a cooldown starting now is assumed.
+func (insta *Instagram) latestCooldown() time.Time {
+	insta.cooldownMu.RLock()
+	defer insta.cooldownMu.RUnlock()
+
+	var until time.Time
+	for _, t := range insta.cooldowns {
+		if t.After(until) {
+			until = t
+		}
+	}
+	if until.Before(time.Now()) {
+		d := insta.cooldownDuration
+		if d == 0 {
+			d = DefaultActionCooldown
+		}
+		until = time.Now().Add(d)
+	}
+	return until
+}
+
+// checkCooldown returns a CooldownError if action is blocked.
+func (insta *Instagram) checkCooldown(action Action) error {
+	if action == "" {
+		return nil
+	}
+	if until := insta.Cooldown(action); !until.IsZero() {
+		return CooldownError{Action: action, Until: until}
+	}
+	return nil
+}
+
+// recordCooldown blocks action if err is a FeedbackRequiredError.
+func (insta *Instagram) recordCooldown(action Action, err error) {
+	var ferr FeedbackRequiredError
+	if action == "" || !errors.As(err, &ferr) {
+		return
+	}
+
+	insta.cooldownMu.RLock()
+	d := insta.cooldownDuration
+	insta.cooldownMu.RUnlock()
+	if d == 0 {
+		d = DefaultActionCooldown
+	}
+
+	until := time.Now().Add(d)
+	insta.SetCooldown(action, until)
+	insta.log().Warn("Action blocked by instagram",
+		"action", action,
+		"until", until,
+		"error", err,
+	)
+}
+
+// exportCooldowns returns the cooldowns that have not expired yet.
+func (insta *Instagram) exportCooldowns() map[Action]time.Time {
+	insta.cooldownMu.RLock()
+	defer insta.cooldownMu.RUnlock()
+
+	var cooldowns map[Action]time.Time
+	now := time.Now()
+	for action, until := range insta.cooldowns {
+		if until.After(now) {
+			if cooldowns == nil {
+				cooldowns = map[Action]time.Time{}
+			}
+			cooldowns[action] = until
+		}
+	}
+	return cooldowns
+}
@@ -0,0 +1,391 @@
+package goinsta
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor is the pagination state of a feed, that can be serialized, e.g. with
+// json.Marshal, to resume the pagination later, like after a restart. Take a
+// cursor with the PageCursor method of a feed after each call to Next, and
+// resume from it with the matching Resume method of Instagram, e.g.
+// Users.PageCursor and Instagram.ResumeUsers.
+//
+// A resumed feed continues with the page after the last page fetched before
+// the cursor was taken. Items that have already been fetched are not
+// included.
+//
+// Timeline, Discover, SearchResult and Conversation have no cursor. The pages
+// of the timeline and discover feeds belong to the session of the app they
+// were fetched in, and search results to the rank token of the query, so
+// their state can't be resumed later. A Conversation pages back from the
+// oldest message it holds, to continue it later keep the Conversation, with
+// its messages, instead of a cursor.
+type Cursor struct {
+	// Kind is the type of feed the cursor belongs to, e.g. "users".
+	Kind string `json:"kind"`
+
+	// State is the opaque pagination state of the feed.
+	State json.RawMessage `json:"state"`
+}
+
+// Kinds of cursors, by feed
+const (
+	cursorUsers      = "users"
+	cursorFeedMedia  = "feed_media"
+	cursorComments   = "comments"
+	cursorHashtag    = "hashtag"
+	cursorIGTV       = "igtv_channel"
+	cursorSavedMedia = "saved_media"
+	cursorCollection = "collection"
+	cursorActivity   = "activity"
+	cursorInbox      = "inbox"
+)
+
+func newCursor(kind string, state interface{}) Cursor {
+	// The states only contain strings, numbers and bools, this can't fail
+	b, _ := json.Marshal(state)
+	return Cursor{Kind: kind, State: b}
+}
+
+// decode decodes the state of the cursor into state, if the cursor is of kind.
+func (c Cursor) decode(kind string, state interface{}) error {
+	if c.Kind != kind {
+		return fmt.Errorf("%w: expected a %s cursor, got %q", ErrCursorKind, kind, c.Kind)
+	}
+	return json.Unmarshal(c.State, state)
+}
+
+// doneErr returns ErrNoMore if the feed has reached its end.
+func doneErr(done bool) error {
+	if done {
+		return ErrNoMore
+	}
+	return nil
+}
+
+type usersCursor struct {
+	Endpoint string            `json:"endpoint"`
+	Query    map[string]string `json:"query"`
+	NextID   string            `json:"next_id"`
+	Done     bool              `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeUsers.
+func (users *Users) PageCursor() Cursor {
+	return newCursor(cursorUsers, usersCursor{
+		Endpoint: users.endpoint,
+		Query:    users.query,
+		NextID:   users.NextID,
+		Done:     users.err == ErrNoMore,
+	})
+}
+
+// ResumeUsers resumes the pagination of a user list, e.g. followers, from a
+// cursor taken with Users.PageCursor.
+func (insta *Instagram) ResumeUsers(c Cursor) (*Users, error) {
+	var state usersCursor
+	if err := c.decode(cursorUsers, &state); err != nil {
+		return nil, err
+	}
+	if state.Query == nil {
+		state.Query = map[string]string{}
+	}
+	return &Users{
+		insta:    insta,
+		err:      doneErr(state.Done),
+		endpoint: state.Endpoint,
+		query:    state.Query,
+		NextID:   state.NextID,
+	}, nil
+}
+
+type feedMediaCursor struct {
+	Endpoint  string `json:"endpoint"`
+	UserID    int64  `json:"user_id"`
+	Timestamp string `json:"timestamp"`
+	NextID    string `json:"next_id"`
+	Done      bool   `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeFeedMedia.
+func (media *FeedMedia) PageCursor() Cursor {
+	return newCursor(cursorFeedMedia, feedMediaCursor{
+		Endpoint:  media.endpoint,
+		UserID:    media.uid,
+		Timestamp: media.timestamp,
+		NextID:    media.GetNextID(),
+		Done:      media.err == ErrNoMore,
+	})
+}
+
+// ResumeFeedMedia resumes the pagination of a feed, e.g. User.Feed, from a
+// cursor taken with FeedMedia.PageCursor.
+func (insta *Instagram) ResumeFeedMedia(c Cursor) (*FeedMedia, error) {
+	var state feedMediaCursor
+	if err := c.decode(cursorFeedMedia, &state); err != nil {
+		return nil, err
+	}
+	return &FeedMedia{
+		insta:     insta,
+		err:       doneErr(state.Done),
+		uid:       state.UserID,
+		endpoint:  state.Endpoint,
+		timestamp: state.Timestamp,
+		NextID:    state.NextID,
+	}, nil
+}
+
+type commentsCursor struct {
+	MediaID   string          `json:"media_id"`
+	Endpoint  string          `json:"endpoint"`
+	NextID    json.RawMessage `json:"next_id,omitempty"`
+	NextMinID json.RawMessage `json:"next_min_id,omitempty"`
+	Done      bool            `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeComments. If the comments don't belong to a post, e.g.
+// because they have been decoded rather than fetched, an empty cursor is
+// returned, which can't be resumed.
+func (comments *Comments) PageCursor() Cursor {
+	if comments.item == nil {
+		return Cursor{}
+	}
+	return newCursor(cursorComments, commentsCursor{
+		MediaID:   comments.item.GetID(),
+		Endpoint:  comments.endpoint,
+		NextID:    comments.NextID,
+		NextMinID: comments.NextMinID,
+		Done:      comments.err == ErrNoMore,
+	})
+}
+
+// ResumeComments resumes the pagination of the comments of a post, from a
+// cursor taken with Comments.PageCursor. Only the ID of the post the comments
+// belong to is restored, sync the post to fetch the rest.
+func (insta *Instagram) ResumeComments(c Cursor) (*Comments, error) {
+	var state commentsCursor
+	if err := c.decode(cursorComments, &state); err != nil {
+		return nil, err
+	}
+	comments := newComments(&Item{insta: insta, ID: state.MediaID})
+	comments.err = doneErr(state.Done)
+	comments.endpoint = state.Endpoint
+	comments.NextID = state.NextID
+	comments.NextMinID = state.NextMinID
+	return comments, nil
+}
+
+type hashtagCursor struct {
+	Name     string                     `json:"name"`
+	PageInfo map[string]hashtagPageInfo `json:"page_info"`
+	Done     bool                       `json:"done"`
+}
+
+// PageCursor returns the pagination state of all tabs, to resume from with
+// Instagram.ResumeHashtag.
+func (h *Hashtag) PageCursor() Cursor {
+	return newCursor(cursorHashtag, hashtagCursor{
+		Name:     h.Name,
+		PageInfo: h.PageInfo,
+		Done:     h.err == ErrNoMore,
+	})
+}
+
+// ResumeHashtag resumes the pagination of a hashtag, from a cursor taken with
+// Hashtag.PageCursor.
+func (insta *Instagram) ResumeHashtag(c Cursor) (*Hashtag, error) {
+	var state hashtagCursor
+	if err := c.decode(cursorHashtag, &state); err != nil {
+		return nil, err
+	}
+	h := insta.NewHashtag(state.Name)
+	h.err = doneErr(state.Done)
+	for tab, info := range state.PageInfo {
+		h.PageInfo[tab] = info
+	}
+	return h, nil
+}
+
+type igtvCursor struct {
+	ID     string `json:"id"`
+	NextID string `json:"next_id"`
+	Done   bool   `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeIGTVChannel.
+func (igtv *IGTVChannel) PageCursor() Cursor {
+	return newCursor(cursorIGTV, igtvCursor{
+		ID:     igtv.id,
+		NextID: igtv.GetNextID(),
+		Done:   igtv.err == ErrNoMore,
+	})
+}
+
+// ResumeIGTVChannel resumes the pagination of an IGTV channel, from a cursor
+// taken with IGTVChannel.PageCursor.
+func (insta *Instagram) ResumeIGTVChannel(c Cursor) (*IGTVChannel, error) {
+	var state igtvCursor
+	if err := c.decode(cursorIGTV, &state); err != nil {
+		return nil, err
+	}
+	return &IGTVChannel{
+		insta:  insta,
+		id:     state.ID,
+		err:    doneErr(state.Done),
+		NextID: state.NextID,
+	}, nil
+}
+
+type savedMediaCursor struct {
+	Endpoint string `json:"endpoint"`
+	NextID   string `json:"next_id"`
+	Done     bool   `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeSavedMedia.
+func (media *SavedMedia) PageCursor() Cursor {
+	return newCursor(cursorSavedMedia, savedMediaCursor{
+		Endpoint: media.endpoint,
+		NextID:   media.GetNextID(),
+		Done:     media.err == ErrNoMore,
+	})
+}
+
+// ResumeSavedMedia resumes the pagination of the saved posts, from a cursor
+// taken with SavedMedia.PageCursor.
+func (insta *Instagram) ResumeSavedMedia(c Cursor) (*SavedMedia, error) {
+	var state savedMediaCursor
+	if err := c.decode(cursorSavedMedia, &state); err != nil {
+		return nil, err
+	}
+	return &SavedMedia{
+		insta:    insta,
+		err:      doneErr(state.Done),
+		endpoint: state.Endpoint,
+		NextID:   state.NextID,
+	}, nil
+}
+
+type collectionCursor struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	NextID string  `json:"next_id"`
+	All    *Cursor `json:"all,omitempty"`
+	Done   bool    `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeCollection.
+func (c *Collection) PageCursor() Cursor {
+	state := collectionCursor{
+		ID:     c.ID,
+		Name:   c.Name,
+		NextID: c.GetNextID(),
+		Done:   c.err == ErrNoMore,
+	}
+	if c.all != nil {
+		all := c.all.PageCursor()
+		state.All = &all
+	}
+	return newCursor(cursorCollection, state)
+}
+
+// ResumeCollection resumes the pagination of a collection, from a cursor
+// taken with Collection.PageCursor.
+func (insta *Instagram) ResumeCollection(cursor Cursor) (*Collection, error) {
+	var state collectionCursor
+	if err := cursor.decode(cursorCollection, &state); err != nil {
+		return nil, err
+	}
+	c := &Collection{
+		insta:  insta,
+		err:    doneErr(state.Done),
+		ID:     state.ID,
+		Name:   state.Name,
+		NextID: state.NextID,
+	}
+	if state.All != nil {
+		all, err := insta.ResumeSavedMedia(*state.All)
+		if err != nil {
+			return nil, err
+		}
+		c.all = all
+	}
+	return c, nil
+}
+
+type activityCursor struct {
+	NextID      string  `json:"next_id"`
+	LastChecked float64 `json:"last_checked"`
+	FirstRecTs  float64 `json:"first_record_timestamp"`
+	Done        bool    `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeActivity.
+func (act *Activity) PageCursor() Cursor {
+	return newCursor(cursorActivity, activityCursor{
+		NextID:      act.NextID,
+		LastChecked: act.LastChecked,
+		FirstRecTs:  act.FirstRecTs,
+		Done:        act.err == ErrNoMore,
+	})
+}
+
+// ResumeActivity resumes the pagination of the recent activity, from a cursor
+// taken with Activity.PageCursor.
+func (insta *Instagram) ResumeActivity(c Cursor) (*Activity, error) {
+	var state activityCursor
+	if err := c.decode(cursorActivity, &state); err != nil {
+		return nil, err
+	}
+	act := newActivity(insta)
+	act.err = doneErr(state.Done)
+	// The first page has already been fetched, and marked as seen
+	act.Status = "ok"
+	act.NextID = state.NextID
+	act.LastChecked = state.LastChecked
+	act.FirstRecTs = state.FirstRecTs
+	return act, nil
+}
+
+type inboxCursor struct {
+	Cursor   string `json:"cursor"`
+	HasOlder bool   `json:"has_older"`
+	SeqID    int64  `json:"seq_id"`
+	Done     bool   `json:"done"`
+}
+
+// PageCursor returns the pagination state, to resume from with
+// Instagram.ResumeInbox.
+func (inbox *Inbox) PageCursor() Cursor {
+	return newCursor(cursorInbox, inboxCursor{
+		Cursor:   inbox.Cursor,
+		HasOlder: inbox.HasOlder,
+		SeqID:    inbox.SeqID,
+		Done:     inbox.err == ErrNoMore,
+	})
+}
+
+// ResumeInbox resumes the pagination of the inbox, from a cursor taken with
+// Inbox.PageCursor. The returned inbox is separate from Instagram.Inbox.
+func (insta *Instagram) ResumeInbox(c Cursor) (*Inbox, error) {
+	var state inboxCursor
+	if err := c.decode(cursorInbox, &state); err != nil {
+		return nil, err
+	}
+	return &Inbox{
+		insta:    insta,
+		err:      doneErr(state.Done),
+		initial:  true,
+		Cursor:   state.Cursor,
+		HasOlder: state.HasOlder,
+		SeqID:    state.SeqID,
+	}, nil
+}
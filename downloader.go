@@ -0,0 +1,566 @@
+package goinsta
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifestFile is the name of the manifest in the directory of a Downloader.
+const manifestFile = "manifest.json"
+
+// defaultDownloadWorkers is the number of concurrent downloads, if not
+// configured.
+const defaultDownloadWorkers = 4
+
+// Downloader mirrors the media of users into a local directory, see
+// Downloader.SyncUser. It records everything it has downloaded in a manifest,
+// so syncing again only downloads new media. Downloads are written to a .part
+// file first, and interrupted downloads are resumed where they stopped.
+//
+//	d, err := insta.NewDownloader("media")
+//	if err != nil {
+//		return err
+//	}
+//	stats, err := d.SyncUser(user)
+type Downloader struct {
+	insta *Instagram
+	dir   string
+
+	// Workers is the maximum number of concurrent downloads. Defaults to 4.
+	Workers int
+
+	// Full walks the feed and IGTV posts to the end, instead of stopping at
+	// the first post that has been downloaded before, e.g. to fill gaps left
+	// by failed downloads.
+	Full bool
+
+	mu       sync.Mutex
+	manifest Manifest
+}
+
+// Manifest records the media a Downloader has downloaded, by media ID. It is
+// stored as manifest.json in the directory of the Downloader.
+type Manifest struct {
+	Media map[string]*ManifestEntry `json:"media"`
+}
+
+// ManifestEntry describes a downloaded media item.
+type ManifestEntry struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"user_id"`
+
+	// Source is where the media has been found: "feed", "stories", "igtv",
+	// or "highlights/<highlight id>"
+	Source string `json:"source"`
+
+	TakenAt      int64          `json:"taken_at"`
+	Caption      string         `json:"caption"`
+	Files        []ManifestFile `json:"files"`
+	DownloadedAt int64          `json:"downloaded_at"`
+}
+
+// ManifestFile is a file of a media item. Carousels have a file for each
+// slide, other media a single file.
+type ManifestFile struct {
+	// Path is relative to the directory of the Downloader, with forward
+	// slashes
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// DownloadStats counts the media items a sync went through.
+type DownloadStats struct {
+	Downloaded int
+	// Skipped items had been downloaded before
+	Skipped int
+	Failed  int
+}
+
+// NewDownloader creates a downloader that mirrors media into dir. If dir
+// holds the manifest of an earlier sync, it is loaded.
+func (insta *Instagram) NewDownloader(dir string) (*Downloader, error) {
+	d := &Downloader{
+		insta:    insta,
+		dir:      dir,
+		manifest: Manifest{Media: map[string]*ManifestEntry{}},
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return d, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &d.manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if d.manifest.Media == nil {
+		d.manifest.Media = map[string]*ManifestEntry{}
+	}
+	return d, nil
+}
+
+// Manifest returns a copy of the manifest.
+func (d *Downloader) Manifest() Manifest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m := Manifest{Media: make(map[string]*ManifestEntry, len(d.manifest.Media))}
+	for id, entry := range d.manifest.Media {
+		e := *entry
+		e.Files = append([]ManifestFile(nil), entry.Files...)
+		m.Media[id] = &e
+	}
+	return m
+}
+
+// SyncUser mirrors the feed, stories, highlights and IGTV posts of user into
+// <dir>/<username>/<source>, and saves the manifest. Items that fail to
+// download don't stop the sync, their errors are joined into the returned
+// error.
+func (d *Downloader) SyncUser(user *User) (*DownloadStats, error) {
+	return d.SyncUserContext(d.insta.Context(), user)
+}
+
+// SyncUserContext is like SyncUser, but the sync will be aborted when the
+// context is cancelled.
+func (d *Downloader) SyncUserContext(ctx context.Context, user *User) (*DownloadStats, error) {
+	s := &downloadSync{
+		d:     d,
+		ctx:   ctx,
+		user:  user,
+		jobs:  make(chan downloadJob),
+		stats: &DownloadStats{},
+	}
+	workers := d.Workers
+	if workers <= 0 {
+		workers = defaultDownloadWorkers
+	}
+	wg := &sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range s.jobs {
+				s.download(job)
+			}
+		}()
+	}
+
+	s.feed()
+	s.stories()
+	s.highlights()
+	s.igtv()
+	close(s.jobs)
+	wg.Wait()
+
+	if err := d.saveManifest(); err != nil {
+		s.fail(err)
+	}
+	return s.stats, errors.Join(s.errs...)
+}
+
+// downloadSync is the state of a single Downloader.SyncUser call.
+type downloadSync struct {
+	d    *Downloader
+	ctx  context.Context
+	user *User
+	jobs chan downloadJob
+
+	mu    sync.Mutex
+	stats *DownloadStats
+	errs  []error
+}
+
+type downloadJob struct {
+	source string
+	item   *Item
+}
+
+func (s *downloadSync) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+// walk queues the items for download. Feeds list the newest items first, so
+// if stopAtKnown is set, the walk stops at the first item in the manifest.
+func (s *downloadSync) walk(source string, it *Iterator[*Item], stopAtKnown bool) {
+	for it.Next() {
+		item := it.Item()
+		if stopAtKnown && s.d.known(item.GetID()) {
+			return
+		}
+		select {
+		case s.jobs <- downloadJob{source: source, item: item}:
+		case <-s.ctx.Done():
+			s.fail(s.ctx.Err())
+			return
+		}
+	}
+	if err := it.Error(); err != nil {
+		s.fail(fmt.Errorf("failed to fetch %s: %w", source, err))
+	}
+}
+
+func (s *downloadSync) feed() {
+	it := s.user.Feed().Iter(IterOptions{Context: s.ctx})
+	s.walk("feed", it, !s.d.Full)
+}
+
+func (s *downloadSync) stories() {
+	stories, err := s.user.Stories()
+	if err != nil {
+		s.fail(fmt.Errorf("failed to fetch stories: %w", err))
+		return
+	}
+	s.walk("stories", s.items(stories.Reel.Items), false)
+}
+
+func (s *downloadSync) highlights() {
+	reels, err := s.user.Highlights()
+	if err != nil {
+		s.fail(fmt.Errorf("failed to fetch highlights: %w", err))
+		return
+	}
+	for _, reel := range reels {
+		if len(reel.Items) == 0 {
+			if err := reel.Sync(); err != nil {
+				s.fail(fmt.Errorf("failed to fetch highlight %s: %w", reel.Title, err))
+				continue
+			}
+		}
+		id := strings.TrimPrefix(formatID(reel.ID), "highlight:")
+		s.walk(path.Join("highlights", id), s.items(reel.Items), false)
+	}
+}
+
+func (s *downloadSync) igtv() {
+	igtv, err := s.user.IGTV()
+	if err != nil && !errors.Is(err, ErrNoMore) {
+		s.fail(fmt.Errorf("failed to fetch igtv: %w", err))
+		return
+	}
+	s.walk("igtv", igtv.Iter(IterOptions{Context: s.ctx}), !s.d.Full)
+}
+
+// items returns an iterator over items that have already been fetched.
+func (s *downloadSync) items(items []*Item) *Iterator[*Item] {
+	return newIterator(s.d.insta, items, nil, itemTime, []IterOptions{{Context: s.ctx}})
+}
+
+// download downloads a single item, unless it has been downloaded before.
+func (s *downloadSync) download(job downloadJob) {
+	downloaded, err := s.d.download(s.ctx, s.user, job.source, job.item)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case err != nil:
+		s.stats.Failed++
+		s.errs = append(s.errs, fmt.Errorf("failed to download %s: %w", job.item.GetID(), err))
+	case downloaded:
+		s.stats.Downloaded++
+	default:
+		s.stats.Skipped++
+	}
+}
+
+// known reports whether the item with id is in the manifest.
+func (d *Downloader) known(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.manifest.Media[id] != nil
+}
+
+// download downloads the files of item, and reports whether anything has
+// been downloaded. Items in the manifest whose files are intact are skipped,
+// files that no longer match the manifest are downloaded again. Files that
+// exist, but aren't in the manifest, e.g. after a crash, are added to it
+// without downloading them again.
+func (d *Downloader) download(ctx context.Context, user *User, source string, item *Item) (bool, error) {
+	id := item.GetID()
+	d.mu.Lock()
+	entry := d.manifest.Media[id]
+	d.mu.Unlock()
+	if entry != nil && d.intact(entry) {
+		return false, nil
+	}
+	recorded := map[string]ManifestFile{}
+	if entry != nil {
+		for _, f := range entry.Files {
+			recorded[f.Path] = f
+		}
+	}
+
+	urls := mediaURLs(item)
+	if len(urls) == 0 {
+		return false, ErrNoMedia
+	}
+
+	dir := userDir(user)
+	entry = &ManifestEntry{
+		ID:      id,
+		UserID:  user.ID,
+		Source:  source,
+		TakenAt: item.TakenAt,
+		Caption: item.Caption.Text,
+	}
+	var downloaded bool
+	for i, url := range urls {
+		name := id
+		if len(urls) > 1 {
+			name = fmt.Sprintf("%s_%d", id, i+1)
+		}
+		rel := path.Join(dir, source, name+mediaExt(url))
+		dst := filepath.Join(d.dir, filepath.FromSlash(rel))
+
+		if f, ok := recorded[rel]; ok && !d.matches(f) {
+			if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return downloaded, err
+			}
+		}
+		if _, err := os.Stat(dst); errors.Is(err, fs.ErrNotExist) {
+			if err := d.insta.downloadFile(ctx, url, dst); err != nil {
+				return downloaded, err
+			}
+			downloaded = true
+		} else if err != nil {
+			return downloaded, err
+		}
+
+		size, sum, err := hashFile(dst)
+		if err != nil {
+			return downloaded, err
+		}
+		entry.Files = append(entry.Files, ManifestFile{Path: rel, Size: size, SHA256: sum})
+	}
+	entry.DownloadedAt = time.Now().Unix()
+
+	d.mu.Lock()
+	d.manifest.Media[id] = entry
+	d.mu.Unlock()
+	return downloaded, nil
+}
+
+// intact reports whether all files of entry exist, with the size and
+// checksum recorded.
+func (d *Downloader) intact(entry *ManifestEntry) bool {
+	for _, f := range entry.Files {
+		if !d.matches(f) {
+			return false
+		}
+	}
+	return len(entry.Files) > 0
+}
+
+// matches reports whether f exists, with the size and checksum recorded.
+func (d *Downloader) matches(f ManifestFile) bool {
+	size, sum, err := hashFile(filepath.Join(d.dir, filepath.FromSlash(f.Path)))
+	return err == nil && size == f.Size && sum == f.SHA256
+}
+
+// saveManifest writes the manifest, replacing the old one at once.
+func (d *Downloader) saveManifest() error {
+	d.mu.Lock()
+	b, err := json.MarshalIndent(d.manifest, "", "  ")
+	d.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(d.dir, 0o755); err != nil {
+		return err
+	}
+	dst := filepath.Join(d.dir, manifestFile)
+	if err := os.WriteFile(dst+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(dst+".tmp", dst)
+}
+
+// downloadFile downloads url to dst. The download is written to dst.part
+// first, and if that exists, the download is resumed from where it stopped.
+// The URL is stored in dst.part.url, to only resume downloads of the same
+// file. URLs are compared by path, as the host and the signed query of CDN
+// URLs change between fetches.
+func (insta *Instagram) downloadFile(ctx context.Context, url, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	part := dst + ".part"
+	if !samePartSource(part+".url", url) {
+		if err := os.Remove(part); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.WriteFile(part+".url", []byte(url), 0o644); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := insta.c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Resuming, unless the server sent another range
+		if start, ok := contentRangeStart(resp.Header); !ok || start != offset {
+			resp.Body.Close()
+			f.Close()
+			if err := os.Remove(part); err != nil {
+				return err
+			}
+			if offset == 0 {
+				return fmt.Errorf("unexpected content range: %s", resp.Header.Get("Content-Range"))
+			}
+			return insta.downloadFile(ctx, url, dst)
+		}
+	case http.StatusOK:
+		// The server doesn't support ranges, start over
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The part file is complete already
+		if offset == 0 {
+			return fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+	default:
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		if _, err := io.Copy(f, resp.Body); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return err
+	}
+	return os.Remove(part + ".url")
+}
+
+// samePartSource reports whether the partial download with the URL stored in
+// file, is a download of url.
+func samePartSource(file, url string) bool {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	stored, err := neturl.Parse(string(b))
+	if err != nil {
+		return false
+	}
+	u, err := neturl.Parse(url)
+	return err == nil && stored.Path == u.Path
+}
+
+// contentRangeStart returns the first byte of the range in the Content-Range
+// header of a 206 response, e.g. "bytes 100-199/200".
+func contentRangeStart(h http.Header) (int64, bool) {
+	v, ok := strings.CutPrefix(h.Get("Content-Range"), "bytes ")
+	if !ok {
+		return 0, false
+	}
+	v, _, ok = strings.Cut(v, "-")
+	if !ok {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(v, 10, 64)
+	return start, err == nil
+}
+
+// mediaURLs returns the URLs of the best quality versions of the media of
+// item, one for each slide of carousels.
+func mediaURLs(item *Item) []string {
+	var urls []string
+	switch item.MediaType {
+	case 1:
+		urls = append(urls, GetBest(item.Images.Versions))
+	case 2:
+		urls = append(urls, GetBest(item.Videos))
+	case 8:
+		for i := range item.CarouselMedia {
+			urls = append(urls, mediaURLs(&item.CarouselMedia[i])...)
+		}
+	}
+	for _, url := range urls {
+		if url == "" {
+			return nil
+		}
+	}
+	return urls
+}
+
+// mediaExt returns the file extension of the media at url, e.g. ".jpg".
+func mediaExt(url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return ""
+	}
+	return path.Ext(u.Path)
+}
+
+func userDir(user *User) string {
+	if user.Username != "" {
+		return user.Username
+	}
+	return strconv.FormatInt(user.ID, 10)
+}
+
+func hashFile(name string) (int64, string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,246 @@
+package goinsta
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Davincible/goinsta/v3/utilities"
+	"golang.org/x/crypto/scrypt"
+)
+
+// encryptedSessionVersion is the version of the envelope encrypted sessions
+// are exported in. Increment it when changing the format, and keep support
+// for decrypting older versions.
+const encryptedSessionVersion = 1
+
+// scrypt parameters used to derive a key from a passphrase.
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Limits of the scrypt parameters accepted when decrypting. The parameters
+// are read from the envelope, and would otherwise allow a crafted session to
+// exhaust memory or CPU. With these limits scrypt uses at most 1 GiB.
+const (
+	scryptMaxN = 1 << 20
+	scryptMaxR = 8
+	scryptMaxP = 16
+)
+
+const (
+	kdfScrypt = "scrypt"
+	kdfNone   = "none"
+)
+
+// SessionKey is the key an exported session is encrypted with, see
+// Instagram.ExportEncrypted. Create one with Passphrase or RawKey.
+type SessionKey struct {
+	passphrase []byte
+	key        []byte
+}
+
+// Passphrase returns a key derived from a passphrase with scrypt. A new salt
+// is generated for every export.
+func Passphrase(passphrase string) SessionKey {
+	return SessionKey{passphrase: []byte(passphrase)}
+}
+
+// RawKey returns a key that is used directly as AES key. It must be 16, 24 or
+// 32 bytes long, e.g. generated with crypto/rand.
+func RawKey(key []byte) SessionKey {
+	return SessionKey{key: key}
+}
+
+// encryptedSession is the envelope an encrypted session is exported in. The
+// config is encrypted with AES-GCM.
+type encryptedSession struct {
+	Version int    `json:"goinsta_encrypted"`
+	KDF     string `json:"kdf"`
+
+	// scrypt parameters, only set if the key was derived from a passphrase
+	N    int    `json:"n,omitempty"`
+	R    int    `json:"r,omitempty"`
+	P    int    `json:"p,omitempty"`
+	Salt []byte `json:"salt,omitempty"`
+
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+	Tag   []byte `json:"tag"`
+}
+
+// additionalData binds the ciphertext to the envelope, so the key derivation
+// parameters can't be tampered with.
+func (e *encryptedSession) additionalData() []byte {
+	return []byte(fmt.Sprintf("goinsta:%d:%s:%d:%d:%d:%x", e.Version, e.KDF, e.N, e.R, e.P, e.Salt))
+}
+
+func (e *encryptedSession) deriveKey(key SessionKey) ([]byte, error) {
+	switch e.KDF {
+	case kdfNone:
+		if key.key == nil {
+			return nil, errors.New("session was encrypted with a raw key, not a passphrase")
+		}
+		return key.key, nil
+	case kdfScrypt:
+		if key.passphrase == nil {
+			return nil, errors.New("session was encrypted with a passphrase, not a raw key")
+		}
+		if err := e.checkScryptParams(); err != nil {
+			return nil, err
+		}
+		return scrypt.Key(key.passphrase, e.Salt, e.N, e.R, e.P, 32)
+	default:
+		return nil, fmt.Errorf("unsupported key derivation function %q", e.KDF)
+	}
+}
+
+// checkScryptParams rejects scrypt parameters that are invalid, or exceed the
+// limits.
+func (e *encryptedSession) checkScryptParams() error {
+	if e.N <= 1 || e.N&(e.N-1) != 0 || e.N > scryptMaxN {
+		return fmt.Errorf("%w: invalid scrypt parameter N=%d", ErrWrongSessionKey, e.N)
+	}
+	if e.R <= 0 || e.R > scryptMaxR || e.P <= 0 || e.P > scryptMaxP {
+		return fmt.Errorf("%w: invalid scrypt parameters r=%d, p=%d", ErrWrongSessionKey, e.R, e.P)
+	}
+	return nil
+}
+
+// isEncryptedSession reports whether b is an encrypted session envelope.
+func isEncryptedSession(b []byte) bool {
+	var e struct {
+		Version int `json:"goinsta_encrypted"`
+	}
+	return json.Unmarshal(b, &e) == nil && e.Version > 0
+}
+
+// EncryptConfig encrypts a config with key, into a versioned envelope that can
+// be decrypted with DecryptConfig.
+func EncryptConfig(config ConfigFile, key SessionKey) ([]byte, error) {
+	plain, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &encryptedSession{Version: encryptedSessionVersion, KDF: kdfNone}
+	if key.passphrase != nil {
+		e.KDF, e.N, e.R, e.P = kdfScrypt, scryptN, scryptR, scryptP
+		e.Salt = make([]byte, 16)
+		if _, err := rand.Read(e.Salt); err != nil {
+			return nil, err
+		}
+	}
+	aesKey, err := e.deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Nonce, e.Data, e.Tag, err = utilities.AESGCMEncrypt(aesKey, plain, e.additionalData())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// DecryptConfig decrypts a config encrypted with EncryptConfig. If the key is
+// wrong, ErrWrongSessionKey is returned.
+func DecryptConfig(b []byte, key SessionKey) (*ConfigFile, error) {
+	e := &encryptedSession{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, err
+	}
+	if e.Version == 0 {
+		return nil, errors.New("session is not encrypted")
+	}
+	if e.Version > encryptedSessionVersion {
+		return nil, fmt.Errorf("unsupported encrypted session version %d, please upgrade goinsta", e.Version)
+	}
+
+	aesKey, err := e.deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := utilities.AESGCMDecrypt(aesKey, e.Nonce, e.Data, e.Tag, e.additionalData())
+	if err != nil {
+		return nil, ErrWrongSessionKey
+	}
+
+	config := &ConfigFile{}
+	if err := json.Unmarshal(plain, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ExportEncrypted exports the session like Export, encrypted with key.
+func (insta *Instagram) ExportEncrypted(path string, key SessionKey) error {
+	b, err := EncryptConfig(insta.ExportConfig(), key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o600)
+}
+
+// ExportEncryptedIO exports the session like ExportIO, encrypted with key.
+func (insta *Instagram) ExportEncryptedIO(writer io.Writer, key SessionKey) error {
+	b, err := EncryptConfig(insta.ExportConfig(), key)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(b)
+	return err
+}
+
+// ExportAsEncryptedBase64String exports the session like
+// ExportAsBase64String, encrypted with key.
+func (insta *Instagram) ExportAsEncryptedBase64String(key SessionKey) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := insta.ExportEncryptedIO(buf, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// ImportEncrypted imports a session exported with ExportEncrypted.
+//
+// Add optional bool:true parameter to prevent account sync on import, see
+// ImportConfig.
+func ImportEncrypted(path string, key SessionKey, args ...interface{}) (*Instagram, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportEncryptedReader(f, key, args...)
+}
+
+// ImportEncryptedReader imports a session exported with ExportEncryptedIO.
+func ImportEncryptedReader(r io.Reader, key SessionKey, args ...interface{}) (*Instagram, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	config, err := DecryptConfig(b, key)
+	if err != nil {
+		return nil, err
+	}
+	return ImportConfig(*config, args...)
+}
+
+// ImportFromEncryptedBase64String imports a session exported with
+// ExportAsEncryptedBase64String.
+func ImportFromEncryptedBase64String(base64String string, key SessionKey, args ...interface{}) (*Instagram, error) {
+	b, err := base64.StdEncoding.DecodeString(base64String)
+	if err != nil {
+		return nil, err
+	}
+	return ImportEncryptedReader(bytes.NewReader(b), key, args...)
+}
@@ -42,6 +42,36 @@ var (
 	errNoAcc = errors.New("no account found")
 )
 
+// EnvPassphrase is the environment variable holding the passphrase to encrypt
+// and decrypt INSTAGRAM_BASE64_ configs with. If set, EnvProvision exports
+// encrypted configs, so they can be committed or shared safely. Plain configs
+// are still imported.
+//
+// The passphrase is only read from the environment, never from the .env file.
+const EnvPassphrase = "GOINSTA_SESSION_PASSPHRASE"
+
+// envExport exports insta as base64 config, encrypted if EnvPassphrase is set.
+func envExport(insta *Instagram) (string, error) {
+	if p := os.Getenv(EnvPassphrase); p != "" {
+		return insta.ExportAsEncryptedBase64String(Passphrase(p))
+	}
+	return insta.ExportAsBase64String()
+}
+
+// envImport imports a base64 config, decrypting it with EnvPassphrase if it
+// has been encrypted.
+func envImport(base64String string) (*Instagram, error) {
+	insta, err := ImportFromBase64String(base64String, true)
+	if errors.Is(err, ErrSessionEncrypted) {
+		p := os.Getenv(EnvPassphrase)
+		if p == "" {
+			return nil, fmt.Errorf("%w, set %s", err, EnvPassphrase)
+		}
+		return ImportFromEncryptedBase64String(base64String, Passphrase(p), true)
+	}
+	return insta, err
+}
+
 // EnvRandAcc will check the environment variables, and the .env file in
 //   the current working directory (unless another path has been provided),
 //   for either a base64 encoded goinsta config, or plain credentials.
@@ -107,6 +137,9 @@ func EnvRandLogin(path ...string) (string, string, error) {
 // This function will add to the .env:
 //   INSTAGRAM_BASE64_<name>="..."
 //
+// If the GOINSTA_SESSION_PASSPHRASE environment variable is set, the configs
+// are encrypted with it, see EnvPassphrase.
+//
 func EnvProvision(path string, refresh ...bool) error {
 	// By default, skip exisitng accounts
 	refreshFlag := len(refresh) == 0 || (len(refresh) > 0 && !refresh[0])
@@ -134,7 +167,7 @@ func EnvProvision(path string, refresh ...bool) error {
 		}
 
 		// Export Config
-		enc, err := insta.ExportAsBase64String()
+		enc, err := envExport(insta)
 		if err != nil {
 			return err
 		}
@@ -254,7 +287,7 @@ func getRandAcc(path ...string) (*Instagram, error) {
 	r := rand.Intn(len(accounts))
 
 	// load account config
-	insta, err := ImportFromBase64String(accounts[r].Base64, true)
+	insta, err := envImport(accounts[r].Base64)
 	if err != nil {
 		return nil, err
 	}
@@ -294,7 +327,7 @@ func EnvLoadAccs(p ...string) ([]*Instagram, error) {
 	accs, _, err := envLoadAccs(p...)
 
 	for _, acc := range accs {
-		insta, err := ImportFromBase64String(acc.Enc.Base64, true)
+		insta, err := envImport(acc.Enc.Base64)
 		if err != nil {
 			return nil, err
 		}
@@ -412,7 +445,7 @@ func parseEnc(line string) (*EnvEncAcc, error) {
 		if encodedString[0] == '"' {
 			encodedString = encodedString[1 : len(encodedString)-1]
 		}
-		insta, err := ImportFromBase64String(encodedString, true)
+		insta, err := envImport(encodedString)
 		if err != nil {
 			return nil, err
 		}
@@ -505,7 +538,7 @@ func accsToFile(path string, accs []*EnvAcc) error {
 }
 
 func (acc *Account) GetEnvEncAcc() (*EnvEncAcc, error) {
-	b, err := acc.insta.ExportAsBase64String()
+	b, err := envExport(acc.insta)
 	return &EnvEncAcc{
 		Username: acc.Username,
 		Base64:   b,
@@ -0,0 +1,171 @@
+package goinsta
+
+import (
+	"fmt"
+	"time"
+)
+
+// APIError holds the details shared by the typed errors returned for failed
+// requests. It is embedded in RateLimitError, AuthError, ChallengeError,
+// NotFoundError, FeedbackRequiredError and ValidationError, which can be
+// matched with errors.As:
+//
+//	var rateLimit goinsta.RateLimitError
+//	if errors.As(err, &rateLimit) {
+//		time.Sleep(rateLimit.RetryAfter)
+//	}
+//
+// The typed errors wrap the sentinel error they replace, if any, so e.g.
+// errors.Is(err, ErrLoginRequired) keeps working. They also wrap the
+// Error400 or ErrorN the response was decoded into.
+//
+// Server errors, with status code 5xx, are returned as a retryable APIError,
+// wrapping the Error503 or ErrorN of the response.
+type APIError struct {
+	// Endpoint is the endpoint that returned the error
+	Endpoint string `json:"-"`
+
+	// StatusCode is the HTTP status code of the response
+	StatusCode int `json:"-"`
+
+	// ErrorType and Message are the error type and message returned by
+	// Instagram, if any
+	ErrorType string `json:"error_type"`
+	Message   string `json:"message"`
+
+	// Retryable reports whether it makes sense to retry the request, and
+	// RetryAfter how long to wait before doing so, if known.
+	Retryable  bool          `json:"-"`
+	RetryAfter time.Duration `json:"-"`
+
+	// Err is the sentinel error, e.g. ErrLoginRequired
+	Err error `json:"-"`
+
+	// cause is the Error400, Error503 or ErrorN the response was decoded into
+	cause error
+}
+
+// RateLimitError is returned if too many requests have been made, with status
+// code 429, or 400 asking to wait a few minutes. It wraps ErrTooManyRequests.
+type RateLimitError struct {
+	APIError
+}
+
+// AuthError is returned if the request failed because the user is not, or
+// can't be, logged in, or isn't allowed to make it. It wraps one of
+// ErrLoginRequired, ErrLoggedOut, ErrBadPassword, Err2FARequired or
+// ErrInvalidCode, or none for any other response with status code 403.
+type AuthError struct {
+	APIError
+}
+
+// NotFoundError is returned if the requested resource doesn't exist, or has
+// been deleted. It wraps ErrNotFound, or ErrMediaDeleted.
+type NotFoundError struct {
+	APIError
+}
+
+// FeedbackRequiredError is returned if Instagram blocked an action, e.g.
+// because it has been performed too often. Retrying doesn't help, the block is
+// usually lifted after a few hours to days. It wraps ErrFeedbackRequired.
+type FeedbackRequiredError struct {
+	APIError
+
+	Spam            bool   `json:"spam"`
+	FeedbackTitle   string `json:"feedback_title"`
+	FeedbackMessage string `json:"feedback_message"`
+	FeedbackURL     string `json:"feedback_url"`
+}
+
+// ValidationError is returned if Instagram rejected the request, with status
+// code 400, for any other reason, e.g. because of invalid parameters.
+type ValidationError struct {
+	APIError
+}
+
+func (e APIError) Error() string {
+	msg := e.Message
+	if e.Err != nil {
+		msg = e.Err.Error()
+		if e.Message != "" && e.Message != e.ErrorType {
+			msg += ": " + e.Message
+		}
+	}
+	if e.ErrorType != "" && e.ErrorType != msg {
+		msg += " (" + e.ErrorType + ")"
+	}
+	return fmt.Sprintf("Error while calling %s, status code %d: %s", e.Endpoint, e.StatusCode, msg)
+}
+
+// Unwrap returns the sentinel error, and the error the response was decoded
+// into.
+func (e APIError) Unwrap() []error {
+	var errs []error
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if e.cause != nil {
+		errs = append(errs, e.cause)
+	}
+	return errs
+}
+
+// RetryHint reports whether it makes sense to retry the request, and how long
+// to wait before doing so, if known. It is used by IsRetryable and
+// RetryPolicy.Delay.
+func (e APIError) RetryHint() (bool, time.Duration) {
+	return e.Retryable, e.RetryAfter
+}
+
+// retryHinter is implemented by all typed errors
+type retryHinter interface {
+	RetryHint() (bool, time.Duration)
+}
+
+// newAPIError creates the details of a typed error from a 400 or 403 response.
+func newAPIError(ierr Error400, sentinel error) APIError {
+	msg := ierr.Message
+	if msg == "" {
+		msg = ierr.Payload.Message
+	}
+	if msg == "" {
+		msg = ierr.DebugInfo.Message
+	}
+	if msg == "" && ierr.Challenge != nil && len(ierr.Challenge.Errors) > 0 {
+		msg = ierr.Challenge.Errors[0]
+	}
+
+	code := ierr.Code
+	if code == 0 {
+		code = 400
+	}
+	return APIError{
+		Endpoint:   ierr.Endpoint,
+		StatusCode: code,
+		ErrorType:  ierr.ErrorType,
+		Message:    msg,
+		Err:        sentinel,
+		cause:      ierr,
+	}
+}
+
+func newChallengeError(ierr Error400, sentinel error) ChallengeError {
+	e := ChallengeError{
+		APIError: newAPIError(ierr, sentinel),
+		Status:   ierr.Status,
+	}
+	e.Message, e.ErrorType = e.APIError.Message, e.APIError.ErrorType
+	c := ierr.Challenge
+	if sentinel == ErrCheckpointRequired {
+		c = &Challenge{URL: ierr.Checkpoint.URL}
+	}
+	if c != nil {
+		e.Challenge.URL = c.URL
+		e.Challenge.APIPath = c.ApiPath
+		e.Challenge.HideWebviewHeader = c.HideWebviewHeader
+		e.Challenge.Lock = c.Lock
+		e.Challenge.Logout = c.Logout
+		e.Challenge.NativeFlow = c.NativeFlow
+	}
+	return e
+}
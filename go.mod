@@ -1,12 +1,15 @@
 module github.com/Davincible/goinsta/v3
 
-go 1.18
+go 1.23
 
 require (
 	github.com/chromedp/cdproto v0.0.0-20220901095120-1a01299a2163
 	github.com/chromedp/chromedp v0.8.5
+	go.etcd.io/bbolt v1.3.10
 )
 
+require golang.org/x/crypto v0.17.0
+
 require (
 	github.com/chromedp/sysutil v1.0.0 // indirect
 	github.com/gobwas/httphead v0.1.0 // indirect
@@ -15,5 +18,5 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/pkg/errors v0.9.1
-	golang.org/x/sys v0.0.0-20220908164124-27713097b956 // indirect
+	golang.org/x/sys v0.15.0 // indirect
 )
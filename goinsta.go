@@ -1,11 +1,13 @@
 package goinsta
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
 	neturl "net/url"
@@ -78,6 +80,8 @@ type Instagram struct {
 	userAgent string
 	// Session Nonce
 	session string
+	// URLs requests are sent to, see SetBaseURLs
+	baseURLs BaseURLs
 
 	// Instagram objects
 
@@ -114,6 +118,9 @@ type Instagram struct {
 
 	c *http.Client
 
+	// Default context used for requests, see SetContext
+	ctx context.Context
+
 	// Set to true to debug reponses
 	Debug bool
 
@@ -123,9 +130,45 @@ type Instagram struct {
 	warnHandler  func(...interface{})
 	debugHandler func(...interface{})
 
+	// Structured logger, see SetLogger
+	logger *slog.Logger
+
 	// Request Wrapper
 	reqWrapper ReqWrapper
 
+	// Middleware added with Use
+	middleware []Middleware
+
+	// Actions blocked by Instagram, see Cooldown
+	cooldownMu       sync.RWMutex
+	cooldowns        map[Action]time.Time
+	cooldownDuration time.Duration
+
+	// Store the session is saved to when it changes, see SetSessionStore
+	sessionMu    sync.Mutex
+	sessionStore SessionStore
+	// Pending save of refreshed headers, see SetSessionSaveDelay
+	sessionDelay time.Duration
+	sessionTimer *time.Timer
+
+	// Login again when the session expires, see SetAutoRelogin
+	reloginMu   sync.Mutex
+	reloginPass string
+	// Closed when the login in progress completes, nil if none is
+	reloginDone chan struct{}
+	// Authorization header of the session that expired
+	reloginAuth string
+	reloginErr  error
+
+	// Solves challenges and checkpoints, see SetChallengeSolver
+	challengeSolver ChallengeSolver
+
+	// Asked for verification codes, see SetCodeProvider
+	codeProvider CodeProvider
+
+	// Rate limiter consulted before every request
+	rateLimiter RateLimiter
+
 	// Proxy string
 	proxy         string
 	proxyInsecure bool
@@ -140,22 +183,37 @@ func defaultHandler(args ...interface{}) {
 	fmt.Println(args...)
 }
 
+// SetInfoHandler sets the handler for info messages. It is not used if a
+// logger has been set with SetLogger.
 func (insta *Instagram) SetInfoHandler(f func(...interface{})) {
 	insta.infoHandler = f
 }
 
+// SetWarnHandler sets the handler for warnings. It is not used if a logger has
+// been set with SetLogger.
 func (insta *Instagram) SetWarnHandler(f func(...interface{})) {
 	insta.warnHandler = f
 }
 
+// SetDebugHandler sets the handler for debug messages, which are only sent if
+// Debug is true. It is not used if a logger has been set with SetLogger.
 func (insta *Instagram) SetDebugHandler(f func(...interface{})) {
 	insta.debugHandler = f
 }
 
+// SetWrapper sets the request wrapper, which is called after the error
+// handlers of the middleware, see Use. By default DefaultWrapper is used.
 func (insta *Instagram) SetWrapper(fn ReqWrapper) {
 	insta.reqWrapper = fn
 }
 
+// SetRateLimiter sets a rate limiter that will be consulted before every
+// request. See NewTokenBucketLimiter for a limiter with per-endpoint budgets.
+// Pass nil to disable rate limiting.
+func (insta *Instagram) SetRateLimiter(rl RateLimiter) {
+	insta.rateLimiter = rl
+}
+
 // SetHTTPClient sets http client.  This further allows users to use this functionality
 // for HTTP testing using a mocking HTTP client Transport, which avoids direct calls to
 // the Instagram, instead of returning mocked responses.
@@ -204,7 +262,7 @@ func (insta *Instagram) SetDevice(device Device) {
 // SetCookieJar sets the Cookie Jar. This further allows to use a custom implementation
 // of a cookie jar which may be backed by a different data store such as redis.
 func (insta *Instagram) SetCookieJar(jar http.CookieJar) error {
-	url, err := neturl.Parse(instaAPIUrl)
+	url, err := neturl.Parse(insta.baseURLs.API)
 	if err != nil {
 		return err
 	}
@@ -240,6 +298,7 @@ func New(username, password string, totp_seed ...string) *Instagram {
 		xmidMu:        &sync.RWMutex{},
 		device:        GalaxyS10,
 		userAgent:     createUserAgent(GalaxyS10),
+		baseURLs:      DefaultBaseURLs(),
 		c: &http.Client{
 			Transport: &http.Transport{
 				Proxy: http.ProxyFromEnvironment,
@@ -290,6 +349,15 @@ func (insta *Instagram) SetTOTPSeed(seed string) {
 	}
 }
 
+// TOTPKey returns the seed used to generate 2FA codes, e.g. to add it to an
+// authenticator app with its otpauth:// URI. Returns nil if no seed is set.
+func (insta *Instagram) TOTPKey() *utilities.OTPKey {
+	if insta.totp == nil || insta.totp.Seed == "" {
+		return nil
+	}
+	return utilities.NewTOTPKey("Instagram", insta.user, insta.totp.Seed)
+}
+
 // SetProxy sets proxy for connection.
 func (insta *Instagram) SetProxy(url string, insecure bool, forceHTTP2 bool) error {
 	insta.proxy = url
@@ -323,7 +391,9 @@ func (insta *Instagram) Save() error {
 }
 
 func (insta *Instagram) ExportConfig() ConfigFile {
+	urls := insta.baseURLs
 	config := ConfigFile{
+		Version:       ConfigVersion,
 		ID:            insta.Account.ID,
 		User:          insta.user,
 		DeviceID:      insta.dID,
@@ -338,6 +408,8 @@ func (insta *Instagram) ExportConfig() ConfigFile {
 		Device:        insta.device,
 		TOTP:          insta.totp,
 		SessionNonce:  insta.session,
+		BaseURLs:      &urls,
+		Cooldowns:     insta.exportCooldowns(),
 	}
 
 	setHeaders := func(key, value interface{}) bool {
@@ -382,6 +454,10 @@ func ImportReader(r io.Reader, args ...interface{}) (*Instagram, error) {
 		return nil, err
 	}
 
+	if isEncryptedSession(bytes) {
+		return nil, ErrSessionEncrypted
+	}
+
 	config := ConfigFile{}
 	err = json.Unmarshal(bytes, &config)
 	if err != nil {
@@ -396,6 +472,10 @@ func ImportReader(r io.Reader, args ...interface{}) (*Instagram, error) {
 //
 // This function does not set proxy automatically. Use SetProxy after this call.
 func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
+	if err := migrateConfig(&config); err != nil {
+		return nil, err
+	}
+
 	insta := &Instagram{
 		user:          config.User,
 		totp:          config.TOTP,
@@ -426,9 +506,19 @@ func ImportConfig(config ConfigFile, args ...interface{}) (*Instagram, error) {
 		privacyRequested: utilities.NewABool(),
 		pubKeyID:         -1,
 		session:          config.SessionNonce,
+		cooldowns:        config.Cooldowns,
 	}
 	insta.userAgent = createUserAgent(insta.device)
 
+	// Configs exported before base URLs were configurable don't contain them
+	if config.BaseURLs != nil {
+		if err := insta.SetBaseURLs(*config.BaseURLs); err != nil {
+			return nil, err
+		}
+	} else {
+		insta.baseURLs = DefaultBaseURLs()
+	}
+
 	for k, v := range config.HeaderOptions {
 		insta.headerOptions.Store(k, v)
 	}
@@ -496,7 +586,7 @@ func (insta *Instagram) Login(password ...string) (err error) {
 		if errIsFatal(err) {
 			return err
 		}
-		insta.warnHandler("Non fatal error while fetching prefill:", err)
+		insta.log().Warn("Non fatal error while fetching prefill", "error", err)
 	}
 
 	err = insta.contactPrefill()
@@ -504,7 +594,7 @@ func (insta *Instagram) Login(password ...string) (err error) {
 		if errIsFatal(err) {
 			return err
 		}
-		insta.warnHandler("Non fatal error while fetching contact prefill:", err)
+		insta.log().Warn("Non fatal error while fetching contact prefill", "error", err)
 	}
 
 	err = insta.sync()
@@ -586,7 +676,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching account family:", err)
+			insta.log().Warn("Non fatal error while fetching account family", "error", err)
 		}
 	}(wg)
 
@@ -598,7 +688,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching ndx steps:", err)
+			insta.log().Warn("Non fatal error while fetching ndx steps", "error", err)
 		}
 	}(wg)
 
@@ -621,7 +711,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching notify badge", err)
+			insta.log().Warn("Non fatal error while fetching notify badge", "error", err)
 		}
 	}(wg)
 
@@ -633,7 +723,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching banyan", err)
+			insta.log().Warn("Non fatal error while fetching banyan", "error", err)
 		}
 	}(wg)
 
@@ -645,7 +735,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching blocked media", err)
+			insta.log().Warn("Non fatal error while fetching blocked media", "error", err)
 		}
 	}(wg)
 
@@ -658,7 +748,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching cool downs", err)
+			insta.log().Warn("Non fatal error while fetching cool downs", "error", err)
 		}
 	}(wg)
 
@@ -670,8 +760,8 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching explore page",
-				insta.Discover.Error())
+			insta.log().Warn("Non fatal error while fetching explore page",
+				"error", insta.Discover.Error())
 		}
 	}(wg)
 
@@ -683,7 +773,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching config", err)
+			insta.log().Warn("Non fatal error while fetching config", "error", err)
 		}
 	}(wg)
 
@@ -696,7 +786,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while fetching bootstrap user scores", err)
+			insta.log().Warn("Non fatal error while fetching bootstrap user scores", "error", err)
 		}
 	}(wg)
 
@@ -719,7 +809,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while sending ad id", err)
+			insta.log().Warn("Non fatal error while sending ad id", "error", err)
 		}
 	}(wg)
 
@@ -731,7 +821,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while calling store client push permissions", err)
+			insta.log().Warn("Non fatal error while calling store client push permissions", "error", err)
 		}
 	}(wg)
 
@@ -754,7 +844,7 @@ func (insta *Instagram) OpenApp() (err error) {
 				errChan <- err
 				return
 			}
-			insta.warnHandler("Non fatal error while calling contact point signal:", err)
+			insta.log().Warn("Non fatal error while calling contact point signal", "error", err)
 		}
 	}(wg)
 
@@ -838,6 +928,7 @@ func (insta *Instagram) parseLogin(body []byte) error {
 	insta.Account.insta = insta
 	insta.session = res.SessionNonce
 	insta.rankToken = strconv.FormatInt(insta.Account.ID, 10) + "_" + insta.uuid
+	insta.sessionChanged()
 
 	return nil
 }
@@ -933,6 +1024,7 @@ func (insta *Instagram) zrToken() error {
 	insta.xmidMu.Lock()
 	insta.xmidExpiry = int64(t + ttl)
 	insta.xmidMu.Unlock()
+	insta.sessionChanged()
 
 	return err
 }
@@ -1009,7 +1101,7 @@ func (insta *Instagram) sync(args ...map[string]string) error {
 
 	id, err := strconv.Atoi(keyID)
 	if err != nil {
-		insta.warnHandler(fmt.Errorf("Failed to parse public key id: %s", err))
+		insta.log().Warn("Failed to parse public key id", "error", err)
 	}
 	insta.pubKey = key
 	insta.pubKeyID = id
@@ -1163,6 +1255,23 @@ func (insta *Instagram) SetTimeout(t time.Duration) {
 	insta.c.Timeout = t
 }
 
+// SetContext sets the default context used for all requests made by this
+// instance. Cancelling it will abort in-flight requests, pagination, uploads
+// and challenge flows. To use a context for a single call, use one of the
+// *Context methods, e.g. User.FollowContext or FeedMedia.NextContext.
+func (insta *Instagram) SetContext(ctx context.Context) {
+	insta.ctx = ctx
+}
+
+// Context returns the default context used for requests. If none has been
+// set with SetContext, context.Background is returned.
+func (insta *Instagram) Context() context.Context {
+	if insta == nil || insta.ctx == nil {
+		return context.Background()
+	}
+	return insta.ctx
+}
+
 // GetMedia returns media specified by id.
 //
 // The argument can be int64 or string
@@ -0,0 +1,195 @@
+package goinstatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// challenge is a challenge a user has to solve before being able to login.
+type challenge struct {
+	nonce string
+	// code is the security code sent, empty if the user only needs to
+	// confirm it was them
+	code string
+	// step is the current step of the challenge
+	step string
+}
+
+// RequireChallenge makes the next login of user fail with challenge_required,
+// until the challenge is solved. If code is empty, the user only needs to
+// confirm it was them. Otherwise, code is the security code "sent" by SMS or
+// email, that needs to be submitted.
+func (s *Server) RequireChallenge(user int64, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	step := "select_verify_method"
+	if code == "" {
+		step = "delta_login_review"
+	}
+	s.challenges[user] = &challenge{
+		nonce: "n" + itoa(s.newID()),
+		code:  code,
+		step:  step,
+	}
+}
+
+// RequireConsent makes all requests of user fail with checkpoint_required,
+// until the cookie consent has been accepted through the consent flow.
+func (s *Server) RequireConsent(user int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.consent[user] = consentScreens
+}
+
+// Screens of the consent flow, in order
+var consentScreens = []string{"qp_intro", "tos_and_two_age_button"}
+
+// HasChallenge reports whether user has a challenge left to solve.
+func (s *Server) HasChallenge(user int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.challenges[user] != nil
+}
+
+func (s *Server) challengeRequired(w http.ResponseWriter, u *User, c *challenge) {
+	path := fmt.Sprintf("/challenge/%d/%s/", u.ID, c.nonce)
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"message": "challenge_required",
+		"challenge": map[string]interface{}{
+			"url":                 "https://i.instagram.com" + path,
+			"api_path":            path,
+			"hide_webview_header": true,
+			"lock":                true,
+			"logout":              false,
+			"native_flow":         true,
+		},
+		"status":     "fail",
+		"error_type": "checkpoint_challenge_required",
+	})
+}
+
+// challengeFor returns the challenge addressed by the request path.
+func (s *Server) challengeFor(w http.ResponseWriter, r *request) (*User, *challenge) {
+	id, _ := strconv.ParseInt(r.params[0], 10, 64)
+	c := s.challenges[id]
+	if c == nil || c.nonce != r.params[1] {
+		writeJSON(w, http.StatusNotFound, fail("challenge not found"))
+		return nil, nil
+	}
+	return s.users[id], c
+}
+
+func (s *Server) challengeState(w http.ResponseWriter, r *request) {
+	u, c := s.challengeFor(w, r)
+	if c == nil {
+		return
+	}
+	s.writeChallengeStep(w, u, c)
+}
+
+func (s *Server) writeChallengeStep(w http.ResponseWriter, u *User, c *challenge) {
+	data := map[string]interface{}{}
+	switch c.step {
+	case "delta_login_review":
+		data["choice"] = "0"
+	case "select_verify_method":
+		data["choice"] = "1"
+		data["email"] = "a***@example.com"
+		data["phone_number"] = "+1 *** ***-**-00"
+	case "verify_email":
+		data["contact_point"] = "a***@example.com"
+		data["form_type"] = "email"
+		data["resend_delay"] = 60
+	case "verify_sms":
+		data["contact_point"] = "+1 *** ***-**-00"
+		data["form_type"] = "phone_number"
+		data["resend_delay"] = 60
+	}
+
+	// Instagram sends the challenge context as a string
+	ctx, _ := json.Marshal(map[string]interface{}{
+		"step_name":           c.step,
+		"nonce_code":          c.nonce,
+		"user_id":             u.ID,
+		"is_stateless":        false,
+		"challenge_type_enum": "UNKNOWN",
+	})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"step_name":         c.step,
+		"step_data":         data,
+		"user_id":           u.ID,
+		"nonce_code":        c.nonce,
+		"challenge_context": string(ctx),
+		"status":            "ok",
+	})
+}
+
+func (s *Server) challengeSubmit(w http.ResponseWriter, r *request) {
+	u, c := s.challengeFor(w, r)
+	if c == nil {
+		return
+	}
+
+	switch c.step {
+	case "delta_login_review":
+		// It was me
+		if r.form["choice"] != "0" {
+			writeJSON(w, http.StatusBadRequest, fail("account locked"))
+			return
+		}
+		delete(s.challenges, u.ID)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"action": "close", "status": "ok"})
+
+	case "select_verify_method":
+		c.step = "verify_email"
+		if r.form["choice"] == "0" {
+			c.step = "verify_sms"
+		}
+		s.writeChallengeStep(w, u, c)
+
+	default:
+		if r.form["security_code"] != c.code {
+			writeJSON(w, http.StatusBadRequest, fail("Please check the code we sent you and try again."))
+			return
+		}
+		delete(s.challenges, u.ID)
+
+		token := s.newSession(u.ID)
+		w.Header().Set("Ig-Set-Authorization", "Bearer IGT:2:"+token)
+		w.Header().Set("Ig-Set-Ig-U-Ds-User-Id", itoa(u.ID))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"logged_in_user": userJSON(u),
+			"action":         "close",
+			"status":         "ok",
+		})
+	}
+}
+
+func (s *Server) checkpointRequired(w http.ResponseWriter) {
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"message":          "checkpoint_required",
+		"checkpoint_url":   "https://i.instagram.com/privacy/checks/?next=instagram%3A%2F%2Fexplore",
+		"lock":             false,
+		"flow_render_type": 0,
+		"status":           "fail",
+	})
+}
+
+func (s *Server) consentFlow(w http.ResponseWriter, r *request) {
+	screens := s.consent[r.viewer.ID]
+	if len(screens) == 0 || r.form["current_screen_key"] != screens[0] {
+		writeJSON(w, http.StatusBadRequest, fail("unexpected consent screen"))
+		return
+	}
+	if len(screens) == 1 {
+		delete(s.consent, r.viewer.ID)
+	} else {
+		s.consent[r.viewer.ID] = screens[1:]
+	}
+	s.ok(w, r)
+}
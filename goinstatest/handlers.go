@@ -0,0 +1,784 @@
+package goinstatest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type route struct {
+	method  string
+	pattern *regexp.Regexp
+	// auth routes can only be called with a valid session
+	auth   bool
+	handle func(s *Server, w http.ResponseWriter, r *request)
+}
+
+// request is a parsed API request.
+type request struct {
+	*http.Request
+
+	// viewer is the logged in user, nil if not logged in
+	viewer *User
+	// params are the submatches of the route pattern
+	params []string
+	// form contains both the query, the form values, and the signed body
+	form map[string]string
+	body []byte
+}
+
+var routes = []route{
+	{"GET", regexp.MustCompile(`^zr/token/result/$`), false, (*Server).zrToken},
+	{"POST", regexp.MustCompile(`^launcher/sync/$`), false, (*Server).sync},
+	{"POST", regexp.MustCompile(`^accounts/login/$`), false, (*Server).login},
+	{"POST", regexp.MustCompile(`^accounts/two_factor_login/$`), false, (*Server).twoFactorLogin},
+	{"POST", regexp.MustCompile(`^accounts/logout/$`), true, (*Server).logout},
+	{"POST", regexp.MustCompile(`^accounts/generate_two_factor_totp_key/$`), true, (*Server).generateTOTPKey},
+	{"POST", regexp.MustCompile(`^accounts/enable_totp_two_factor/$`), true, (*Server).enableTOTP},
+	{"POST", regexp.MustCompile(`^accounts/disable_totp_two_factor/$`), true, (*Server).disableTOTP},
+	{"POST", regexp.MustCompile(`^accounts/regen_backup_codes/$`), true, (*Server).regenBackupCodes},
+	{"POST", regexp.MustCompile(`^accounts/account_security_info/$`), true, (*Server).securityInfo},
+	{"GET", regexp.MustCompile(`^challenge/(\d+)/([^/]+)/$`), false, (*Server).challengeState},
+	{"POST", regexp.MustCompile(`^challenge/(\d+)/([^/]+)/$`), false, (*Server).challengeSubmit},
+	{"POST", regexp.MustCompile(`^consent/existing_user_flow/$`), true, (*Server).consentFlow},
+	{"GET", regexp.MustCompile(`^accounts/current_user/$`), true, (*Server).currentUser},
+	{"POST", regexp.MustCompile(`^feed/timeline/$`), true, (*Server).timeline},
+	{"POST", regexp.MustCompile(`^feed/reels_tray/$`), true, (*Server).reelsTray},
+	{"GET", regexp.MustCompile(`^feed/user/(\d+)/$`), true, (*Server).userFeed},
+	{"GET", regexp.MustCompile(`^users/(\d+)/info/$`), true, (*Server).userInfo},
+	{"GET", regexp.MustCompile(`^users/([^/]+)/usernameinfo/$`), true, (*Server).userInfo},
+	{"GET", regexp.MustCompile(`^users/search/$`), true, (*Server).searchUsers},
+	{"GET", regexp.MustCompile(`^fbsearch/places/$`), true, (*Server).searchPlaces},
+	{"POST", regexp.MustCompile(`^friendships/create/(\d+)/$`), true, (*Server).follow},
+	{"POST", regexp.MustCompile(`^friendships/destroy/(\d+)/$`), true, (*Server).unfollow},
+	{"GET", regexp.MustCompile(`^friendships/show/(\d+)/$`), true, (*Server).friendship},
+	{"GET", regexp.MustCompile(`^friendships/(\d+)/(followers|following)/$`), true, (*Server).followList},
+	{"GET", regexp.MustCompile(`^direct_v2/inbox/$`), true, (*Server).inbox},
+	{"GET", regexp.MustCompile(`^direct_v2/pending_inbox/$`), true, (*Server).pendingInbox},
+	{"GET", regexp.MustCompile(`^direct_v2/threads/get_by_participants/$`), true, (*Server).threadByParticipants},
+	{"POST", regexp.MustCompile(`^direct_v2/threads/broadcast/text/$`), true, (*Server).sendText},
+	{"GET", regexp.MustCompile(`^direct_v2/threads/(\d+)/$`), true, (*Server).thread},
+	{"POST", regexp.MustCompile(`^rupload_ig(photo|video)/([^/]+)$`), true, (*Server).rupload},
+	{"GET", regexp.MustCompile(`^rupload_igvideo/([^/]+)$`), true, (*Server).ok},
+	{"POST", regexp.MustCompile(`^media/configure/$`), true, (*Server).configure},
+	{"GET", regexp.MustCompile(`^media/(\d+)\.jpg$`), false, (*Server).download},
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	endpoint := strings.TrimPrefix(req.URL.Path, "/")
+	endpoint = strings.TrimPrefix(endpoint, "api/v1/")
+	endpoint = strings.TrimPrefix(endpoint, "api/v2/")
+
+	r, err := parseRequest(req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, fail(err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.viewer = s.viewer(req)
+	for _, route := range routes {
+		if route.method != req.Method {
+			continue
+		}
+		m := route.pattern.FindStringSubmatch(endpoint)
+		if m == nil {
+			continue
+		}
+		if route.auth && r.viewer == nil {
+			writeJSON(w, http.StatusForbidden, map[string]interface{}{
+				"message":     "login_required",
+				"error_title": "You've Been Logged Out",
+				"status":      "fail",
+			})
+			return
+		}
+		if r.viewer != nil && len(s.consent[r.viewer.ID]) > 0 && route.auth &&
+			!strings.HasPrefix(endpoint, "consent/") {
+			s.checkpointRequired(w)
+			return
+		}
+		r.params = m[1:]
+		route.handle(s, w, r)
+		return
+	}
+
+	// Not modelled by the fake server
+	s.ok(w, r)
+}
+
+func parseRequest(req *http.Request) (*request, error) {
+	r := &request{
+		Request: req,
+		form:    map[string]string{},
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		if body, err = io.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+	r.body = body
+
+	values := req.URL.Query()
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range form {
+			values[k] = v
+		}
+	}
+	for k := range values {
+		r.form[k] = values.Get(k)
+	}
+
+	if signed, ok := r.form["signed_body"]; ok {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(signed, "SIGNATURE.")), &data); err != nil {
+			return nil, fmt.Errorf("failed to parse signed body: %w", err)
+		}
+		for k, v := range data {
+			switch v := v.(type) {
+			case string:
+				r.form[k] = v
+			default:
+				b, _ := json.Marshal(v)
+				r.form[k] = string(b)
+			}
+		}
+	}
+	return r, nil
+}
+
+func (s *Server) viewer(req *http.Request) *User {
+	auth := req.Header.Get("Authorization")
+	i := strings.LastIndex(auth, ":")
+	if i == -1 {
+		return nil
+	}
+	id, ok := s.sessions[auth[i+1:]]
+	if !ok {
+		return nil
+	}
+	return s.users[id]
+}
+
+func (s *Server) ok(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+func (s *Server) zrToken(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"token": map[string]interface{}{
+			"ttl":          3600,
+			"request_time": time.Now().Unix(),
+		},
+		"status": "ok",
+	})
+}
+
+func (s *Server) sync(w http.ResponseWriter, r *request) {
+	w.Header().Set("Ig-Set-Password-Encryption-Pub-Key", s.publicKey())
+	w.Header().Set("Ig-Set-Password-Encryption-Key-Id", strconv.Itoa(pubKeyID))
+	s.ok(w, r)
+}
+
+func (s *Server) login(w http.ResponseWriter, r *request) {
+	u := s.userByName(r.form["username"])
+	if u == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message":    "The username you entered doesn't appear to belong to an account.",
+			"error_type": "invalid_user",
+			"status":     "fail",
+		})
+		return
+	}
+
+	password, err := s.decryptPassword(r.form["enc_password"])
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, fail(err.Error()))
+		return
+	}
+	if password != u.Password {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message":    "The password you entered is incorrect. Please try again.",
+			"error_type": "bad_password",
+			"status":     "fail",
+		})
+		return
+	}
+
+	if c := s.challenges[u.ID]; c != nil {
+		s.challengeRequired(w, u, c)
+		return
+	}
+	if tf := s.twoFactor[u.ID]; tf.enabled() {
+		s.twoFactorRequired(w, u, tf)
+		return
+	}
+	s.loggedIn(w, u)
+}
+
+// loggedIn starts a new session for u, and responds like a successful login.
+func (s *Server) loggedIn(w http.ResponseWriter, u *User) {
+	token := s.newSession(u.ID)
+	w.Header().Set("Ig-Set-Authorization", "Bearer IGT:2:"+token)
+	w.Header().Set("Ig-Set-Ig-U-Ds-User-Id", itoa(u.ID))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"logged_in_user":      userJSON(u),
+		"session_flush_nonce": token[:12],
+		"status":              "ok",
+	})
+}
+
+// decryptPassword reverses utilities.EncryptPassword.
+func (s *Server) decryptPassword(enc string) (string, error) {
+	parts := strings.SplitN(enc, ":", 4)
+	if len(parts) != 4 || parts[0] != "#PWD_INSTAGRAM" {
+		return "", errors.New("invalid enc_password")
+	}
+	b, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", err
+	}
+
+	// version, key id, iv, key size, encrypted key, tag, encrypted password
+	if len(b) < 2+12+2 {
+		return "", errors.New("invalid enc_password")
+	}
+	iv := b[2:14]
+	size := int(binary.LittleEndian.Uint16(b[14:16]))
+	if len(b) < 16+size+16 {
+		return "", errors.New("invalid enc_password")
+	}
+	encKey := b[16 : 16+size]
+	tag := b[16+size : 16+size+16]
+	encrypted := b[16+size+16:]
+
+	key, err := rsa.DecryptPKCS1v15(nil, s.key, encKey)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	password, err := gcm.Open(nil, iv, append(encrypted, tag...), []byte(parts[2]))
+	if err != nil {
+		return "", err
+	}
+	return string(password), nil
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *request) {
+	s.expireSessions(r.viewer.ID)
+	s.ok(w, r)
+}
+
+func (s *Server) currentUser(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user":   userJSON(r.viewer),
+		"status": "ok",
+	})
+}
+
+func (s *Server) timeline(w http.ResponseWriter, r *request) {
+	var items []interface{}
+	authors := append(sortedIDs(s.following[r.viewer.ID]), r.viewer.ID)
+	for _, id := range authors {
+		for _, m := range s.userMedia(id) {
+			items = append(items, map[string]interface{}{"media_or_ad": s.mediaJSON(m)})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"feed_items":     items,
+		"num_results":    len(items),
+		"more_available": false,
+		"status":         "ok",
+	})
+}
+
+func (s *Server) reelsTray(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tray":   []interface{}{},
+		"status": "ok",
+	})
+}
+
+func (s *Server) userFeed(w http.ResponseWriter, r *request) {
+	u := s.userParam(w, r)
+	if u == nil {
+		return
+	}
+	media := s.userMedia(u.ID)
+	start, end, next := s.page(len(media), r.form["max_id"])
+
+	items := []interface{}{}
+	for _, m := range media[start:end] {
+		items = append(items, s.mediaJSON(m))
+	}
+	resp := map[string]interface{}{
+		"items":          items,
+		"num_results":    len(items),
+		"more_available": next != "",
+		"status":         "ok",
+	}
+	if next != "" {
+		resp["next_max_id"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *request) {
+	u := s.userParam(w, r)
+	if u == nil {
+		return
+	}
+	user := userJSON(u)
+	user["friendship_status"] = s.friendshipJSON(r.viewer, u)
+	user["follower_count"] = len(s.followers(u.ID))
+	user["following_count"] = len(s.following[u.ID])
+	user["media_count"] = len(s.userMedia(u.ID))
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user":   user,
+		"status": "ok",
+	})
+}
+
+// searchUsers lists the users whose username starts with the query.
+func (s *Server) searchUsers(w http.ResponseWriter, r *request) {
+	ids := map[int64]bool{}
+	for _, u := range s.users {
+		ids[u.ID] = strings.HasPrefix(u.Username, r.form["q"])
+	}
+	users := []interface{}{}
+	for _, id := range sortedIDs(ids) {
+		users = append(users, userJSON(s.users[id]))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"users":       users,
+		"num_results": len(users),
+		"has_more":    false,
+		"status":      "ok",
+	})
+}
+
+// searchPlaces answers with a single place, named after the query.
+func (s *Server) searchPlaces(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"items": []interface{}{
+			map[string]interface{}{
+				"title": r.form["query"],
+				"location": map[string]interface{}{
+					"pk":                 1,
+					"name":               r.form["query"],
+					"external_source":    "facebook_places",
+					"facebook_places_id": 1,
+				},
+			},
+		},
+		"has_more": false,
+		"status":   "ok",
+	})
+}
+
+func (s *Server) follow(w http.ResponseWriter, r *request) {
+	u := s.userParam(w, r)
+	if u == nil {
+		return
+	}
+	s.addFollow(r.viewer.ID, u.ID)
+	s.friendshipResp(w, r.viewer, u)
+}
+
+func (s *Server) unfollow(w http.ResponseWriter, r *request) {
+	u := s.userParam(w, r)
+	if u == nil {
+		return
+	}
+	s.removeFollow(r.viewer.ID, u.ID)
+	s.friendshipResp(w, r.viewer, u)
+}
+
+func (s *Server) friendship(w http.ResponseWriter, r *request) {
+	u := s.userParam(w, r)
+	if u == nil {
+		return
+	}
+	resp := s.friendshipJSON(r.viewer, u)
+	resp["status"] = "ok"
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) friendshipResp(w http.ResponseWriter, viewer, u *User) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"friendship_status": s.friendshipJSON(viewer, u),
+		"status":            "ok",
+	})
+}
+
+func (s *Server) followList(w http.ResponseWriter, r *request) {
+	u := s.userParam(w, r)
+	if u == nil {
+		return
+	}
+	ids := s.followers(u.ID)
+	if r.params[1] == "following" {
+		ids = sortedIDs(s.following[u.ID])
+	}
+
+	users := []interface{}{}
+	for _, id := range ids {
+		f := s.users[id]
+		if q := r.form["query"]; q != "" && !strings.HasPrefix(f.Username, q) {
+			continue
+		}
+		users = append(users, userJSON(f))
+	}
+	start, end, next := s.page(len(users), r.form["max_id"])
+	resp := map[string]interface{}{
+		"users":     users[start:end],
+		"page_size": end - start,
+		"big_list":  next != "",
+		"status":    "ok",
+	}
+	if next != "" {
+		resp["next_max_id"] = next
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// page returns the range of the page starting at maxID, of a list of n
+// items, and the max ID of the next page, which is empty on the last page.
+func (s *Server) page(n int, maxID string) (start, end int, next string) {
+	start, _ = strconv.Atoi(maxID)
+	if start > n || start < 0 {
+		start = n
+	}
+	end = n
+	if s.pageSize > 0 && start+s.pageSize < n {
+		end = start + s.pageSize
+		next = strconv.Itoa(end)
+	}
+	return start, end, next
+}
+
+func (s *Server) inbox(w http.ResponseWriter, r *request) {
+	threads := []interface{}{}
+	for _, t := range s.userThreads(r.viewer.ID) {
+		threads = append(threads, s.threadJSON(t, r.viewer))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"inbox": map[string]interface{}{
+			"threads":      threads,
+			"has_older":    false,
+			"unseen_count": 0,
+		},
+		"seq_id":         s.lastID,
+		"snapshot_at_ms": time.Now().UnixNano() / int64(time.Millisecond),
+		"status":         "ok",
+	})
+}
+
+func (s *Server) pendingInbox(w http.ResponseWriter, r *request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"inbox": map[string]interface{}{
+			"threads":   []interface{}{},
+			"has_older": false,
+		},
+		"seq_id": s.lastID,
+		"status": "ok",
+	})
+}
+
+func (s *Server) threadByParticipants(w http.ResponseWriter, r *request) {
+	var ids []int64
+	if err := json.Unmarshal([]byte(r.form["recipient_users"]), &ids); err != nil || len(ids) != 1 {
+		writeJSON(w, http.StatusBadRequest, fail("invalid recipient_users"))
+		return
+	}
+	resp := map[string]interface{}{"status": "ok"}
+	if t := s.privateThread(r.viewer.ID, ids[0]); t != nil {
+		resp["thread"] = s.threadJSON(t, r.viewer)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) sendText(w http.ResponseWriter, r *request) {
+	var t *Thread
+	if ids := r.form["thread_ids"]; ids != "" {
+		var threads []string
+		if err := json.Unmarshal([]byte(ids), &threads); err != nil || len(threads) != 1 {
+			writeJSON(w, http.StatusBadRequest, fail("invalid thread_ids"))
+			return
+		}
+		t = s.threads[threads[0]]
+		if t == nil || !t.has(r.viewer.ID) {
+			writeJSON(w, http.StatusNotFound, fail("Thread not found"))
+			return
+		}
+	} else {
+		var recipients [][]int64
+		err := json.Unmarshal([]byte(r.form["recipient_users"]), &recipients)
+		if err != nil || len(recipients) != 1 || len(recipients[0]) != 1 {
+			writeJSON(w, http.StatusBadRequest, fail("invalid recipient_users"))
+			return
+		}
+		to := recipients[0][0]
+		if s.users[to] == nil {
+			writeJSON(w, http.StatusNotFound, fail("User not found"))
+			return
+		}
+		if t = s.privateThread(r.viewer.ID, to); t == nil {
+			t = s.newThread(r.viewer.ID, to)
+		}
+	}
+
+	msg := Message{
+		ID:            itoa(s.newID()),
+		UserID:        r.viewer.ID,
+		Text:          r.form["text"],
+		ClientContext: r.form["client_context"],
+		Timestamp:     time.Now(),
+	}
+	t.Messages = append(t.Messages, msg)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"action": "item_ack",
+		"payload": map[string]interface{}{
+			"client_context": msg.ClientContext,
+			"item_id":        msg.ID,
+			"thread_id":      t.ID,
+			"timestamp":      itoa(msg.Timestamp.UnixNano() / int64(time.Microsecond)),
+		},
+		"status":      "ok",
+		"status_code": "200",
+	})
+}
+
+func (s *Server) thread(w http.ResponseWriter, r *request) {
+	t := s.threads[r.params[0]]
+	if t == nil || !t.has(r.viewer.ID) {
+		writeJSON(w, http.StatusNotFound, fail("Thread not found"))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"thread": s.threadJSON(t, r.viewer),
+		"status": "ok",
+	})
+}
+
+func (s *Server) rupload(w http.ResponseWriter, r *request) {
+	var params struct {
+		UploadID string `json:"upload_id"`
+	}
+	if err := json.Unmarshal([]byte(r.Header.Get("X-Instagram-Rupload-Params")), &params); err != nil || params.UploadID == "" {
+		writeJSON(w, http.StatusBadRequest, fail("invalid rupload params"))
+		return
+	}
+	if l := r.Header.Get("X-Entity-Length"); l != "" && l != strconv.Itoa(len(r.body)) {
+		writeJSON(w, http.StatusBadRequest, fail("entity length mismatch"))
+		return
+	}
+
+	s.uploads[params.UploadID] = &upload{data: r.body}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"upload_id":       params.UploadID,
+		"xsharing_nonces": map[string]interface{}{},
+		"status":          "ok",
+	})
+}
+
+func (s *Server) configure(w http.ResponseWriter, r *request) {
+	id := r.form["upload_id"]
+	up := s.uploads[id]
+	if up == nil {
+		writeJSON(w, http.StatusBadRequest, fail("upload_id not found"))
+		return
+	}
+	delete(s.uploads, id)
+
+	m := &Media{
+		ID:      s.newID(),
+		UserID:  r.viewer.ID,
+		Caption: r.form["caption"],
+		Data:    up.data,
+		TakenAt: time.Now(),
+	}
+	if c, _, err := image.DecodeConfig(bytes.NewReader(up.data)); err == nil {
+		m.Width, m.Height = c.Width, c.Height
+	}
+	s.media = append(s.media, m)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"media":  s.mediaJSON(m),
+		"status": "ok",
+	})
+}
+
+func (s *Server) download(w http.ResponseWriter, r *request) {
+	id, _ := strconv.ParseInt(r.params[0], 10, 64)
+	for _, m := range s.media {
+		if m.ID == id {
+			// Supports range requests, to resume downloads
+			w.Header().Set("Content-Type", "image/jpeg")
+			http.ServeContent(w, r.Request, "", m.TakenAt, bytes.NewReader(m.Data))
+			return
+		}
+	}
+	http.NotFound(w, r.Request)
+}
+
+// userParam looks up the user in the first route parameter, which is either
+// an ID or a username. If the user doesn't exist, a 404 is written.
+func (s *Server) userParam(w http.ResponseWriter, r *request) *User {
+	var u *User
+	if id, err := strconv.ParseInt(r.params[0], 10, 64); err == nil {
+		u = s.users[id]
+	} else {
+		u = s.userByName(r.params[0])
+	}
+	if u == nil {
+		writeJSON(w, http.StatusNotFound, fail("User not found"))
+	}
+	return u
+}
+
+func userJSON(u *User) map[string]interface{} {
+	return map[string]interface{}{
+		"pk":          u.ID,
+		"username":    u.Username,
+		"full_name":   u.FullName,
+		"is_private":  u.IsPrivate,
+		"is_verified": false,
+	}
+}
+
+func (s *Server) friendshipJSON(viewer, u *User) map[string]interface{} {
+	return map[string]interface{}{
+		"following":   s.following[viewer.ID][u.ID],
+		"followed_by": s.following[u.ID][viewer.ID],
+		"is_private":  u.IsPrivate,
+	}
+}
+
+func (s *Server) mediaJSON(m *Media) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              fmt.Sprintf("%d_%d", m.ID, m.UserID),
+		"pk":              m.ID,
+		"code":            "fake" + itoa(m.ID),
+		"media_type":      1,
+		"taken_at":        m.TakenAt.Unix(),
+		"original_width":  m.Width,
+		"original_height": m.Height,
+		"user":            userJSON(s.users[m.UserID]),
+		"caption": map[string]interface{}{
+			"pk":         itoa(m.ID),
+			"user_id":    m.UserID,
+			"text":       m.Caption,
+			"created_at": m.TakenAt.Unix(),
+		},
+		"image_versions2": map[string]interface{}{
+			"candidates": []interface{}{
+				map[string]interface{}{
+					"url":    fmt.Sprintf("%s/media/%d.jpg", s.URL, m.ID),
+					"width":  m.Width,
+					"height": m.Height,
+				},
+			},
+		},
+	}
+}
+
+func (s *Server) threadJSON(t *Thread, viewer *User) map[string]interface{} {
+	users := []interface{}{}
+	var names []string
+	for _, id := range t.UserIDs {
+		if id != viewer.ID {
+			users = append(users, userJSON(s.users[id]))
+			names = append(names, s.users[id].Username)
+		}
+	}
+
+	// Newest message first
+	items := []interface{}{}
+	for i := len(t.Messages) - 1; i >= 0; i-- {
+		msg := t.Messages[i]
+		items = append(items, map[string]interface{}{
+			"item_id":        msg.ID,
+			"user_id":        msg.UserID,
+			"timestamp":      msg.Timestamp.UnixNano() / int64(time.Microsecond),
+			"item_type":      "text",
+			"text":           msg.Text,
+			"client_context": msg.ClientContext,
+		})
+	}
+
+	threadType := "private"
+	if len(t.UserIDs) > 2 {
+		threadType = "group"
+	}
+	return map[string]interface{}{
+		"thread_id":        t.ID,
+		"thread_v2_id":     t.ID,
+		"thread_title":     strings.Join(names, ", "),
+		"thread_type":      threadType,
+		"is_group":         len(t.UserIDs) > 2,
+		"users":            users,
+		"viewer_id":        viewer.ID,
+		"items":            items,
+		"last_activity_at": t.lastActivity().UnixNano() / int64(time.Microsecond),
+		"has_older":        false,
+		"has_newer":        false,
+	}
+}
+
+func fail(msg string) map[string]interface{} {
+	return map[string]interface{}{
+		"message": msg,
+		"status":  "fail",
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
@@ -0,0 +1,144 @@
+// Package goinstatest provides an in-process fake of the Instagram private API,
+// to test code built on goinsta end to end without touching Instagram.
+//
+// The server keeps an in-memory model of accounts, friendships, direct message
+// threads and uploaded media. It implements the endpoints used to login,
+// fetch the timeline, (un)follow users, send direct messages and upload
+// photos, as well as the challenge and cookie consent flows. All other
+// endpoints answer with an empty, successful response.
+//
+//	srv := goinstatest.NewServer()
+//	defer srv.Close()
+//	srv.AddUser("alice", "password")
+//
+//	insta := goinsta.New("alice", "password")
+//	srv.Connect(insta)
+//	err := insta.Login()
+package goinstatest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// Server is a fake Instagram API server.
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:1234
+	URL string
+
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu        sync.Mutex
+	lastID    int64
+	users     map[int64]*User
+	sessions  map[string]int64
+	following map[int64]map[int64]bool
+	threads   map[string]*Thread
+	uploads   map[string]*upload
+	media     []*Media
+
+	challenges map[int64]*challenge
+	twoFactor  map[int64]*twoFactor
+	// clockDrift is how far the clock is ahead, when validating TOTP codes
+	clockDrift time.Duration
+	// pageSize is the number of items per page of paginated lists, 0 for
+	// all items on one page
+	pageSize int
+	// Consent screens left to accept, by user
+	consent map[int64][]string
+}
+
+// Password encryption key ID, as sent in the launcher/sync/ response headers.
+const pubKeyID = 41
+
+// NewServer starts a new fake server. Call Close when done.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("goinstatest: failed to generate password encryption key: " + err.Error())
+	}
+
+	s := &Server{
+		key:       key,
+		lastID:    1000,
+		users:     map[int64]*User{},
+		sessions:  map[string]int64{},
+		following: map[int64]map[int64]bool{},
+		threads:   map[string]*Thread{},
+		uploads:   map[string]*upload{},
+
+		challenges: map[int64]*challenge{},
+		twoFactor:  map[int64]*twoFactor{},
+		consent:    map[int64][]string{},
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// SetPageSize sets the number of items on each page of the user feed and
+// follower lists, so pagination can be tested. By default, all items are on
+// one page.
+func (s *Server) SetPageSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pageSize = n
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Connect points insta at the fake server, instead of Instagram.
+func (s *Server) Connect(insta *goinsta.Instagram) {
+	// The URLs are always valid, this can't fail
+	insta.SetBaseURLs(s.BaseURLs())
+}
+
+// BaseURLs returns the URLs to send requests to, to reach the fake server.
+func (s *Server) BaseURLs() goinsta.BaseURLs {
+	return goinsta.BaseURLs{
+		Base:   s.URL + "/",
+		API:    s.URL + "/api/v1/",
+		APIb:   s.URL + "/api/v1/",
+		APIv2:  s.URL + "/api/v2/",
+		APIv2b: s.URL + "/api/v2/",
+		Upload: s.URL + "/",
+	}
+}
+
+func (s *Server) publicKey() string {
+	b, err := x509.MarshalPKIXPublicKey(&s.key.PublicKey)
+	if err != nil {
+		panic("goinstatest: failed to encode public key: " + err.Error())
+	}
+	p := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: b})
+	return base64.StdEncoding.EncodeToString(p)
+}
+
+func (s *Server) newSession(user int64) string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic("goinstatest: failed to generate session token: " + err.Error())
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	s.sessions[token] = user
+	return token
+}
+
+func itoa(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
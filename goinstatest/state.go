@@ -0,0 +1,268 @@
+package goinstatest
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// User is an account registered on the fake server.
+type User struct {
+	ID        int64
+	Username  string
+	Password  string
+	FullName  string
+	IsPrivate bool
+}
+
+// Media is a post uploaded to the fake server.
+type Media struct {
+	ID      int64
+	UserID  int64
+	Caption string
+	Width   int
+	Height  int
+	Data    []byte
+	TakenAt time.Time
+}
+
+// Thread is a direct message conversation.
+type Thread struct {
+	ID       string
+	UserIDs  []int64
+	Messages []Message
+}
+
+// Message is a direct message sent in a thread.
+type Message struct {
+	ID            string
+	UserID        int64
+	Text          string
+	ClientContext string
+	Timestamp     time.Time
+}
+
+// upload is a file uploaded to rupload_*, that has not been configured yet.
+type upload struct {
+	data []byte
+}
+
+// AddUser registers a new account, that can be logged into with password.
+func (s *Server) AddUser(username, password string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := &User{
+		ID:       s.newID(),
+		Username: username,
+		Password: password,
+	}
+	s.users[u.ID] = u
+	return u
+}
+
+// AddMedia adds a post of user, as if it had been uploaded. The ID and UserID
+// of m are set by the server. If TakenAt is zero, the current time is used.
+func (s *Server) AddMedia(user int64, m Media) *Media {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m.ID = s.newID()
+	m.UserID = user
+	if m.TakenAt.IsZero() {
+		m.TakenAt = time.Now()
+	}
+	s.media = append(s.media, &m)
+	c := m
+	return &c
+}
+
+// User returns the account with the username provided, or nil if no such
+// account exists.
+func (s *Server) User(username string) *User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u := s.userByName(username); u != nil {
+		c := *u
+		return &c
+	}
+	return nil
+}
+
+// ExpireSessions logs out all sessions of user, as if they expired. Requests
+// made with them are answered with login_required.
+func (s *Server) ExpireSessions(user int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expireSessions(user)
+}
+
+// IsFollowing reports whether follower follows user.
+func (s *Server) IsFollowing(follower, user int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.following[follower][user]
+}
+
+// Following returns the IDs of the accounts user follows.
+func (s *Server) Following(user int64) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return sortedIDs(s.following[user])
+}
+
+// Followers returns the IDs of the accounts following user.
+func (s *Server) Followers(user int64) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.followers(user)
+}
+
+// Threads returns all direct message threads user is part of, with the most
+// recently active thread first.
+func (s *Server) Threads(user int64) []Thread {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var threads []Thread
+	for _, t := range s.userThreads(user) {
+		c := *t
+		c.UserIDs = append([]int64(nil), t.UserIDs...)
+		c.Messages = append([]Message(nil), t.Messages...)
+		threads = append(threads, c)
+	}
+	return threads
+}
+
+// Media returns all posts uploaded by user, newest first.
+func (s *Server) Media(user int64) []Media {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var media []Media
+	for _, m := range s.userMedia(user) {
+		media = append(media, *m)
+	}
+	return media
+}
+
+func (s *Server) expireSessions(user int64) {
+	for token, id := range s.sessions {
+		if id == user {
+			delete(s.sessions, token)
+		}
+	}
+}
+
+func (s *Server) newID() int64 {
+	s.lastID++
+	return s.lastID
+}
+
+func (s *Server) userByName(username string) *User {
+	for _, u := range s.users {
+		if u.Username == username {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) addFollow(follower, user int64) {
+	if s.following[follower] == nil {
+		s.following[follower] = map[int64]bool{}
+	}
+	s.following[follower][user] = true
+}
+
+func (s *Server) removeFollow(follower, user int64) {
+	delete(s.following[follower], user)
+}
+
+func (s *Server) followers(user int64) []int64 {
+	ids := map[int64]bool{}
+	for follower, following := range s.following {
+		if following[user] {
+			ids[follower] = true
+		}
+	}
+	return sortedIDs(ids)
+}
+
+func (s *Server) userThreads(user int64) []*Thread {
+	var threads []*Thread
+	for _, t := range s.threads {
+		if t.has(user) {
+			threads = append(threads, t)
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].lastActivity().After(threads[j].lastActivity())
+	})
+	return threads
+}
+
+// privateThread returns the one on one thread between a and b, or nil if
+// they have never messaged each other.
+func (s *Server) privateThread(a, b int64) *Thread {
+	for _, t := range s.threads {
+		if len(t.UserIDs) == 2 && t.has(a) && t.has(b) {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *Server) newThread(users ...int64) *Thread {
+	t := &Thread{
+		ID:      strconv.FormatInt(s.newID(), 10),
+		UserIDs: users,
+	}
+	s.threads[t.ID] = t
+	return t
+}
+
+func (s *Server) userMedia(user int64) []*Media {
+	var media []*Media
+	for _, m := range s.media {
+		if m.UserID == user {
+			media = append(media, m)
+		}
+	}
+	sort.Slice(media, func(i, j int) bool {
+		return media[i].ID > media[j].ID
+	})
+	return media
+}
+
+func (t *Thread) has(user int64) bool {
+	for _, id := range t.UserIDs {
+		if id == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Thread) lastActivity() time.Time {
+	if len(t.Messages) == 0 {
+		return time.Time{}
+	}
+	return t.Messages[len(t.Messages)-1].Timestamp
+}
+
+func sortedIDs(set map[int64]bool) []int64 {
+	ids := []int64{}
+	for id, ok := range set {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
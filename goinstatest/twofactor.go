@@ -0,0 +1,231 @@
+package goinstatest
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Davincible/goinsta/v3/utilities"
+)
+
+// twoFactor is the two factor authentication state of a user.
+type twoFactor struct {
+	// code is the code "sent" by SMS, empty if SMS two factor is off
+	code string
+	// seed is the TOTP seed, empty if TOTP two factor is off
+	seed string
+	// pending is the seed generated, but not enabled yet
+	pending   string
+	pendingID int64
+
+	backupCodes []string
+	trusted     []map[string]interface{}
+	// identifier of the pending two factor login, changes every login
+	identifier string
+}
+
+func (tf *twoFactor) enabled() bool {
+	return tf != nil && (tf.code != "" || tf.seed != "")
+}
+
+// EnableTwoFactor enables SMS two factor authentication for user. Logins fail
+// with two_factor_required, until code is submitted.
+func (s *Server) EnableTwoFactor(user int64, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.userTwoFactor(user).code = code
+}
+
+// TOTPSeed returns the TOTP seed of user, or an empty string if TOTP two
+// factor authentication is off.
+func (s *Server) TOTPSeed(user int64) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tf := s.twoFactor[user]; tf != nil {
+		return tf.seed
+	}
+	return ""
+}
+
+func (s *Server) userTwoFactor(user int64) *twoFactor {
+	tf := s.twoFactor[user]
+	if tf == nil {
+		tf = &twoFactor{}
+		s.twoFactor[user] = tf
+	}
+	return tf
+}
+
+func (s *Server) twoFactorRequired(w http.ResponseWriter, u *User, tf *twoFactor) {
+	tf.identifier = "tf" + itoa(s.newID())
+	writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+		"message":             "You'll need to enter a security code to log in.",
+		"two_factor_required": true,
+		"two_factor_info": map[string]interface{}{
+			"pk":                      u.ID,
+			"username":                u.Username,
+			"two_factor_identifier":   tf.identifier,
+			"sms_two_factor_on":       tf.code != "",
+			"totp_two_factor_on":      tf.seed != "",
+			"whatsapp_two_factor_on":  false,
+			"obfuscated_phone_number": "00",
+		},
+		"error_type": "two_factor_required",
+		"status":     "fail",
+	})
+}
+
+func (s *Server) twoFactorLogin(w http.ResponseWriter, r *request) {
+	u := s.userByName(r.form["username"])
+	var tf *twoFactor
+	if u != nil {
+		tf = s.twoFactor[u.ID]
+	}
+	if !tf.enabled() || tf.identifier == "" || r.form["two_factor_identifier"] != tf.identifier {
+		writeJSON(w, http.StatusBadRequest, fail("invalid two factor identifier"))
+		return
+	}
+	if !s.validTwoFactorCode(tf, r.form["verification_code"]) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"message":    "Please check the security code and try again.",
+			"error_type": "sms_code_validation_code_invalid",
+			"status":     "fail",
+		})
+		return
+	}
+	tf.identifier = ""
+	if r.form["trust_this_device"] == "1" {
+		tf.trusted = append(tf.trusted, map[string]interface{}{
+			"device_id":       r.form["device_id"],
+			"device_guid":     r.form["guid"],
+			"device_name":     "Android",
+			"last_login_time": time.Now().Unix(),
+		})
+	}
+	s.loggedIn(w, u)
+}
+
+// valid reports whether code is the SMS code, the current TOTP code, or one
+// of the backup codes, which can only be used once.
+func (s *Server) validTwoFactorCode(tf *twoFactor, code string) bool {
+	switch {
+	case code == "":
+		return false
+	case tf.code != "" && code == tf.code:
+		return true
+	case tf.seed != "" && s.totpValid(tf.seed, code):
+		return true
+	}
+	for i, c := range tf.backupCodes {
+		if c == code {
+			tf.backupCodes = append(tf.backupCodes[:i], tf.backupCodes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// totpValid reports whether code is valid at the time of the server, allowing
+// a period of skew, like Instagram does.
+func (s *Server) totpValid(seed, code string) bool {
+	now := time.Now().Add(s.clockDrift)
+	ok, err := utilities.ValidateTOTP(seed, code, now, utilities.OTPOptions{Skew: 1})
+	return err == nil && ok
+}
+
+// SetClockDrift sets how far the clock of the server is ahead, when validating
+// TOTP codes. Negative durations set the clock behind.
+func (s *Server) SetClockDrift(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clockDrift = d
+}
+
+func (s *Server) generateTOTPKey(w http.ResponseWriter, r *request) {
+	seed := make([]byte, 20)
+	rand.Read(seed)
+
+	tf := s.userTwoFactor(r.viewer.ID)
+	tf.pending = base32.StdEncoding.EncodeToString(seed)
+	tf.pendingID = s.newID()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"totp_seed":    tf.pending,
+		"totp_seed_id": tf.pendingID,
+		"status":       "ok",
+	})
+}
+
+func (s *Server) enableTOTP(w http.ResponseWriter, r *request) {
+	tf := s.userTwoFactor(r.viewer.ID)
+	if tf.pending == "" || r.form["totp_seed_id"] != itoa(tf.pendingID) {
+		writeJSON(w, http.StatusBadRequest, fail("invalid totp seed"))
+		return
+	}
+	if !s.totpValid(tf.pending, r.form["verification_code"]) {
+		writeJSON(w, http.StatusBadRequest, fail("Please check the security code and try again."))
+		return
+	}
+	tf.seed, tf.pending = tf.pending, ""
+	tf.backupCodes = newBackupCodes()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"backup_codes": tf.backupCodes,
+		"status":       "ok",
+	})
+}
+
+func (s *Server) disableTOTP(w http.ResponseWriter, r *request) {
+	tf := s.userTwoFactor(r.viewer.ID)
+	tf.seed = ""
+	if !tf.enabled() {
+		tf.backupCodes = nil
+		tf.trusted = nil
+	}
+	s.ok(w, r)
+}
+
+func (s *Server) regenBackupCodes(w http.ResponseWriter, r *request) {
+	tf := s.userTwoFactor(r.viewer.ID)
+	if !tf.enabled() {
+		writeJSON(w, http.StatusBadRequest, fail("two factor authentication is off"))
+		return
+	}
+	tf.backupCodes = newBackupCodes()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"backup_codes": tf.backupCodes,
+		"status":       "ok",
+	})
+}
+
+func (s *Server) securityInfo(w http.ResponseWriter, r *request) {
+	tf := s.userTwoFactor(r.viewer.ID)
+	trusted := tf.trusted
+	if trusted == nil {
+		trusted = []map[string]interface{}{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"is_phone_confirmed":               tf.code != "",
+		"is_two_factor_enabled":            tf.enabled(),
+		"is_totp_two_factor_enabled":       tf.seed != "",
+		"is_trusted_notifications_enabled": false,
+		"backup_codes":                     tf.backupCodes,
+		"trusted_devices":                  trusted,
+		"status":                           "ok",
+	})
+}
+
+func newBackupCodes() []string {
+	codes := make([]string, 5)
+	for i := range codes {
+		b := make([]byte, 4)
+		rand.Read(b)
+		n := binary.BigEndian.Uint32(b) % 100000000
+		codes[i] = fmt.Sprintf("%04d %04d", n/10000, n%10000)
+	}
+	return codes
+}
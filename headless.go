@@ -1,3 +1,5 @@
+//go:build headless
+
 package goinsta
 
 import (
@@ -5,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
 	"time"
 
@@ -14,6 +17,43 @@ import (
 	"github.com/chromedp/chromedp"
 )
 
+// HeadlessSolver solves challenges and checkpoints in a headless Chrome
+// browser. It is only available if goinsta is built with the headless build
+// tag, and requires Chrome or Chromium to be installed:
+//
+//	go build -tags headless
+//
+// Challenges are opened in a visible browser window, for you to solve, and a
+// screenshot is saved. Cookie consent checkpoints are accepted automatically.
+type HeadlessSolver struct{}
+
+func defaultChallengeSolver() ChallengeSolver {
+	return HeadlessSolver{}
+}
+
+// SelectMethod opens the challenge in the browser, and waits for it to be
+// solved there. No code needs to be submitted afterwards.
+func (HeadlessSolver) SelectMethod(ctx context.Context, c *Challenge) (bool, error) {
+	insta := c.insta
+	insta.log().Warn("Encountered a captcha challenge, goinsta will attempt to open the challenge in a headless chromium browser, and take a screenshot. Please report the details in a github issue.")
+	return false, checkHeadlessErr(insta.openChallenge(ctx, c.URL))
+}
+
+// ReceiveCode is not supported, challenges are solved in the browser.
+func (HeadlessSolver) ReceiveCode(ctx context.Context, c *Challenge) (string, error) {
+	return "", ErrChallengeNoCode
+}
+
+// SubmitCode is not supported, challenges are solved in the browser.
+func (HeadlessSolver) SubmitCode(ctx context.Context, c *Challenge, code string) error {
+	return ErrChallengeNoCode
+}
+
+// AcceptCookies clicks the button to allow all cookies.
+func (HeadlessSolver) AcceptCookies(ctx context.Context, c *Checkpoint) error {
+	return checkHeadlessErr(c.insta.acceptPrivacyCookies(ctx, c.URL))
+}
+
 type headlessOptions struct {
 	// seconds
 	timeout int64
@@ -57,10 +97,9 @@ func printButtons(insta *Instagram) chromedp.Action {
 			}
 			for _, p := range nodes {
 				if len(p.Children) > 0 {
-					insta.infoHandler(
-						fmt.Sprintf("Found button on challenge page: %s\n",
-							p.Children[0].NodeValue,
-						))
+					insta.log().Info("Found button on challenge page",
+						"button", p.Children[0].NodeValue,
+					)
 				}
 			}
 			return nil
@@ -82,7 +121,7 @@ func takeScreenshot(fn string) chromedp.Action {
 		})
 }
 
-func (insta *Instagram) acceptPrivacyCookies(url string) error {
+func (insta *Instagram) acceptPrivacyCookies(ctx context.Context, url string) error {
 	// Looks for the "Allow All Cookies button"
 	selector := `//button[contains(text(),"Allow All Cookies")]`
 
@@ -91,6 +130,7 @@ func (insta *Instagram) acceptPrivacyCookies(url string) error {
 	success := false
 
 	return insta.runHeadless(
+		ctx,
 		&headlessOptions{
 			timeout:     60,
 			showBrowser: false,
@@ -108,12 +148,13 @@ func (insta *Instagram) acceptPrivacyCookies(url string) error {
 	)
 }
 
-func (insta *Instagram) openChallenge(url string) error {
+func (insta *Instagram) openChallenge(ctx context.Context, url string) error {
 	fname := fmt.Sprintf("challenge-screenshot-%d.png", time.Now().Unix())
 
 	success := false
 
 	err := insta.runHeadless(
+		ctx,
 		&headlessOptions{
 			timeout:     300,
 			showBrowser: true,
@@ -134,12 +175,11 @@ func (insta *Instagram) openChallenge(url string) error {
 		return err
 	}
 
-	insta.infoHandler(
-		fmt.Sprintf(
-			"Saved a screenshot of the challenge '%s' to %s, please report it in a github issue so the challenge can be solved automatiaclly.\n",
-			url,
-			fname,
-		))
+	insta.log().Info(
+		"Saved a screenshot of the challenge, please report it in a github issue so the challenge can be solved automatically",
+		"url", url,
+		"file", fname,
+	)
 
 	if !success {
 		return ErrChallengeFailed
@@ -150,7 +190,7 @@ func (insta *Instagram) openChallenge(url string) error {
 // runHeadless takes a list of chromedp actions to perform, wrapped around default
 //   actions that will need to be run for every headless request, such as setting
 //   the cookies and user-agent.
-func (insta *Instagram) runHeadless(options *headlessOptions) error {
+func (insta *Instagram) runHeadless(ctx context.Context, options *headlessOptions) error {
 	if insta.privacyCalled.Get() {
 		return errors.New("Accept privacy cookie method has already been called. Did it not work? please report on a github issue")
 	}
@@ -204,7 +244,7 @@ func (insta *Instagram) runHeadless(options *headlessOptions) error {
 		opts = append(opts, chromedp.Flag("headless", false))
 	}
 
-	ctx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancel := chromedp.NewExecAllocator(ctx, opts...)
 	defer cancel()
 
 	// create chrome instance
@@ -271,3 +311,17 @@ func (insta *Instagram) runHeadless(options *headlessOptions) error {
 	err := chromedp.Run(ctx, append(default_actions, options.tasks))
 	return err
 }
+
+// checkHeadlessErr will return a proper error if a chrome browser was not found.
+func checkHeadlessErr(err error) error {
+	// Check if err = Chrome not found
+	if err != nil {
+		if matched, reErr := regexp.Match("executable file not found", []byte(err.Error())); reErr != nil {
+			return reErr
+		} else if matched {
+			return ErrChromeNotFound
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,7 @@
+//go:build !headless
+
+package goinsta
+
+func defaultChallengeSolver() ChallengeSolver {
+	return &HTTPSolver{}
+}
@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -235,7 +236,7 @@ type VisualMedia struct {
 	ReplayExpiringAtUs int64    `json:"replay_expiring_at_us"`
 }
 
-func (inbox *Inbox) sync(pending bool, params map[string]string) error {
+func (inbox *Inbox) sync(ctx context.Context, pending bool, params map[string]string) error {
 	endpoint := urlInbox
 	if pending {
 		endpoint = urlInboxPending
@@ -244,6 +245,7 @@ func (inbox *Inbox) sync(pending bool, params map[string]string) error {
 	insta := inbox.insta
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  ctx,
 			Endpoint: endpoint,
 			Query:    params,
 		},
@@ -262,7 +264,7 @@ func (inbox *Inbox) sync(pending bool, params map[string]string) error {
 	return nil
 }
 
-func (inbox *Inbox) next(pending bool, params map[string]string) bool {
+func (inbox *Inbox) next(ctx context.Context, pending bool, params map[string]string) bool {
 	endpoint := urlInbox
 	if pending {
 		endpoint = urlInboxPending
@@ -273,6 +275,7 @@ func (inbox *Inbox) next(pending bool, params map[string]string) bool {
 	insta := inbox.insta
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  ctx,
 			Endpoint: endpoint,
 			Query:    params,
 		},
@@ -300,14 +303,20 @@ func (inbox *Inbox) next(pending bool, params map[string]string) bool {
 
 // Sync updates inbox messages.
 func (inbox *Inbox) Sync() error {
+	return inbox.SyncContext(inbox.insta.Context())
+}
+
+// SyncContext is like Sync, but the request will be aborted when the context
+// is cancelled.
+func (inbox *Inbox) SyncContext(ctx context.Context) error {
 	if inbox.initial {
-		return inbox.sync(false, map[string]string{
+		return inbox.sync(ctx, false, map[string]string{
 			"visual_message_return_type": "unseen",
 			"persistentBadging":          "true",
 			"limit":                      "0",
 		})
 	} else {
-		if !inbox.InitialSnapshot() {
+		if !inbox.initialSnapshot(ctx) {
 			if inbox.err != ErrNoMore {
 				return inbox.err
 			}
@@ -318,7 +327,7 @@ func (inbox *Inbox) Sync() error {
 
 // SyncPending updates inbox pending messages.
 func (inbox *Inbox) SyncPending() error {
-	return inbox.sync(true, map[string]string{})
+	return inbox.sync(inbox.insta.Context(), true, map[string]string{})
 }
 
 // New will send a message to a user in an existring message thread if it exists,
@@ -373,6 +382,7 @@ func (c *Conversation) send(query map[string]string) error {
 			Endpoint: urlInboxSend,
 			IsPost:   true,
 			Query:    query,
+			Action:   ActionMessage,
 		},
 	)
 	if err != nil {
@@ -404,7 +414,13 @@ func (inbox *Inbox) Reset() {
 
 // Next allows pagination over message threads.
 func (inbox *Inbox) Next() bool {
-	return inbox.next(false, map[string]string{
+	return inbox.NextContext(inbox.insta.Context())
+}
+
+// NextContext is like Next, but the request will be aborted when the context
+// is cancelled.
+func (inbox *Inbox) NextContext(ctx context.Context) bool {
+	return inbox.next(ctx, false, map[string]string{
 		"persistentBadging": "true",
 		"cursor":            inbox.Cursor,
 	})
@@ -413,8 +429,12 @@ func (inbox *Inbox) Next() bool {
 // InitialSnapshot fetches the initial messages on app open, and is called
 //   from Instagram.OpenApp() automatically.
 func (inbox *Inbox) InitialSnapshot() bool {
+	return inbox.initialSnapshot(inbox.insta.Context())
+}
+
+func (inbox *Inbox) initialSnapshot(ctx context.Context) bool {
 	inbox.initial = true
-	return inbox.next(false, map[string]string{
+	return inbox.next(ctx, false, map[string]string{
 		"visual_message_return_type": "unseen",
 		"thread_message_limit":       "10",
 		"persistentBadging":          "true",
@@ -425,7 +445,7 @@ func (inbox *Inbox) InitialSnapshot() bool {
 
 // NextPending allows pagination over pending messages.
 func (inbox *Inbox) NextPending() bool {
-	return inbox.next(true, map[string]string{
+	return inbox.next(inbox.insta.Context(), true, map[string]string{
 		"cursor": inbox.Cursor,
 	})
 }
@@ -0,0 +1,196 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instrumentation receives telemetry about the API calls goinsta makes, e.g.
+// to emit OpenTelemetry spans and metrics, or Prometheus metrics. goinsta
+// doesn't depend on any backend, implement this interface to connect one, and
+// add it with Instrument.
+//
+//	type tracer struct{ trace.Tracer }
+//
+//	func (t tracer) RequestStarted(ctx context.Context, call *goinsta.APICall) context.Context {
+//		ctx, _ = t.Start(ctx, call.Endpoint)
+//		return ctx
+//	}
+//
+//	func (t tracer) RequestFinished(ctx context.Context, call *goinsta.APICall) {
+//		span := trace.SpanFromContext(ctx)
+//		span.SetAttributes(attribute.Int("http.status_code", call.StatusCode))
+//		if call.Error != nil {
+//			span.SetStatus(codes.Error, call.ErrorKind)
+//		}
+//		span.End()
+//	}
+//
+//	insta.Use(goinsta.Instrument(tracer{otel.Tracer("goinsta")}))
+type Instrumentation interface {
+	// RequestStarted is called before a request is sent. The context returned
+	// is used to send the request, and passed on to RequestFinished, so it
+	// can carry e.g. a span.
+	RequestStarted(ctx context.Context, call *APICall) context.Context
+
+	// RequestFinished is called once the response has been received, or the
+	// request failed. Every request is reported separately, so a request that
+	// is retried is reported once per attempt.
+	RequestFinished(ctx context.Context, call *APICall)
+}
+
+// APICall describes a single request to the Instagram API.
+type APICall struct {
+	// Endpoint is the template the endpoint has been created from, as found
+	// in const.go, e.g. feed/user/%d/ for feed/user/123/
+	Endpoint string
+	Method   string
+
+	// Attempt is 1 for the first time a request is sent, 2 for the first
+	// retry, etc.
+	Attempt int
+
+	// BytesSent is the size of the request body
+	BytesSent int64
+
+	// The fields below are set once the request has finished
+
+	// StatusCode is 0 if no response has been received
+	StatusCode    int
+	BytesReceived int64
+	Latency       time.Duration
+
+	// Error is the error returned by the request, if any. ErrorKind
+	// classifies the error, to use as a low cardinality label, see ErrorKind.
+	Error     error
+	ErrorKind string
+}
+
+type apiCallKey struct{}
+
+// Instrument returns middleware that reports all API calls to inst.
+func Instrument(inst Instrumentation) Middleware {
+	return Middleware{
+		BeforeSend: func(r *Request) error {
+			call := &APICall{
+				Endpoint:  EndpointTemplate(r.Endpoint),
+				Method:    r.Method,
+				Attempt:   r.Attempt,
+				BytesSent: r.ContentLength,
+			}
+			ctx := inst.RequestStarted(r.Context(), call)
+			ctx = context.WithValue(ctx, apiCallKey{}, call)
+			r.Request = r.Request.WithContext(ctx)
+			return nil
+		},
+		AfterReceive: func(r *Response) {
+			ctx := r.Request.Context()
+			call, ok := ctx.Value(apiCallKey{}).(*APICall)
+			if !ok {
+				// Request has been aborted before reaching this middleware
+				return
+			}
+			call.StatusCode = r.StatusCode
+			call.BytesReceived = int64(len(r.Body))
+			call.Latency = r.Latency
+			call.Error = r.Error
+			call.ErrorKind = ErrorKind(r.Error)
+			inst.RequestFinished(ctx, call)
+		},
+	}
+}
+
+var (
+	endpointTemplateRes []*regexp.Regexp
+	endpointIDRe        = regexp.MustCompile(`^\d+(_\d+)?$`)
+)
+
+func init() {
+	for _, t := range endpointTemplates {
+		re := regexp.QuoteMeta(t)
+		re = strings.ReplaceAll(re, "%d", `\d+`)
+		re = strings.ReplaceAll(re, "%s", `[^/]+`)
+		endpointTemplateRes = append(endpointTemplateRes, regexp.MustCompile("^"+re+"$"))
+	}
+}
+
+// EndpointTemplate returns the template an endpoint has been created from,
+// e.g. feed/user/%d/ for feed/user/123/. Endpoints that don't match any of the
+// templates are returned with their query removed, and their IDs replaced by
+// %s, to keep the number of distinct endpoints reported low.
+func EndpointTemplate(endpoint string) string {
+	if i := strings.IndexByte(endpoint, '?'); i != -1 {
+		endpoint = endpoint[:i]
+	}
+	for i, re := range endpointTemplateRes {
+		if re.MatchString(endpoint) {
+			return endpointTemplates[i]
+		}
+	}
+
+	parts := strings.Split(endpoint, "/")
+	for i, p := range parts {
+		if endpointIDRe.MatchString(p) {
+			parts[i] = "%s"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// ErrorKind classifies an error returned by a request, e.g. to count errors by
+// type. It returns an empty string if err is nil.
+func ErrorKind(err error) string {
+	var (
+		e400 Error400
+		eN   ErrorN
+		e503 Error503
+		nerr net.Error
+	)
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrTooManyRequests):
+		return "too_many_requests"
+	case errors.Is(err, ErrChallengeRequired):
+		return "challenge_required"
+	case errors.Is(err, ErrCheckpointRequired):
+		return "checkpoint_required"
+	case errors.Is(err, Err2FARequired):
+		return "two_factor_required"
+	case errors.Is(err, ErrLoginRequired):
+		return "login_required"
+	case errors.Is(err, ErrLoggedOut):
+		return "logged_out"
+	case errors.Is(err, ErrBadPassword):
+		return "bad_password"
+	case errors.Is(err, ErrFeedbackRequired):
+		return "feedback_required"
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrMediaDeleted):
+		return "not_found"
+	case errors.As(err, new(ValidationError)):
+		return "validation"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.As(err, &e400):
+		if e400.Code == 0 {
+			return "error_400"
+		}
+		return "error_" + strconv.Itoa(e400.Code)
+	case errors.As(err, &eN):
+		return "error_" + strconv.Itoa(eN.Code)
+	case errors.As(err, &e503):
+		return "error_503"
+	case errors.As(err, &nerr):
+		return "network"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,498 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"sort"
+	"time"
+)
+
+// IterOptions configures an Iterator.
+type IterOptions struct {
+	// Limit is the maximum number of items to iterate over, 0 for no limit.
+	Limit int
+
+	// StopAt ends the iteration at the first item older than StopAt. Feeds
+	// list the newest items first, so no older items follow. It is ignored for
+	// items without a timestamp, like users.
+	StopAt time.Time
+
+	// Context aborts the iteration when cancelled. Defaults to the context of
+	// the Instagram instance, see SetContext. FeedMedia, Users and Inbox pass
+	// it on to their requests, so cancelling aborts a page being fetched. The
+	// other feeds (Comments, Timeline, Hashtag, Collection, SavedMedia, IGTV,
+	// Conversation, Activity, Discover and Search) fetch their pages without
+	// it, so for them cancellation only takes effect between pages.
+	Context context.Context
+}
+
+// Iterator iterates over the items of a paginated feed one by one, fetching
+// the next page when needed. The Iter method of each feed returns one, e.g.
+// FeedMedia.Iter. The iteration starts with the items the feed has already
+// fetched.
+//
+//	it := user.Feed().Iter(goinsta.IterOptions{Limit: 100})
+//	for it.Next() {
+//		item := it.Item()
+//	}
+//	if err := it.Error(); err != nil {
+//		return err
+//	}
+//
+// Or range over All:
+//
+//	for item := range user.Feed().Iter().All() {
+//	}
+type Iterator[T any] struct {
+	ctx   context.Context
+	opts  IterOptions
+	fetch func(ctx context.Context) ([]T, error)
+	stamp func(T) time.Time
+
+	page []T
+	item T
+	n    int
+	done bool
+	err  error
+}
+
+// newIterator creates an iterator over held, the items already fetched,
+// followed by the pages returned by fetch. Fetch returns the items of the
+// next page, and ErrNoMore once the last page has been fetched. Stamp returns
+// the time of an item, and may be nil.
+func newIterator[T any](
+	insta *Instagram,
+	held []T,
+	fetch func(ctx context.Context) ([]T, error),
+	stamp func(T) time.Time,
+	opts []IterOptions,
+) *Iterator[T] {
+	it := &Iterator[T]{
+		fetch: fetch,
+		stamp: stamp,
+		page:  append([]T(nil), held...),
+	}
+	if len(opts) > 0 {
+		it.opts = opts[0]
+	}
+	it.ctx = it.opts.Context
+	if it.ctx == nil {
+		it.ctx = insta.Context()
+	}
+	return it
+}
+
+// Next advances to the next item, which is then available through Item. It
+// returns false when there are no more items, the limit or StopAt has been
+// reached, or an error occurred, see Error.
+func (it *Iterator[T]) Next() bool {
+	if it.done {
+		return false
+	}
+	if it.opts.Limit > 0 && it.n >= it.opts.Limit {
+		return it.stop(nil)
+	}
+
+	for len(it.page) == 0 {
+		if it.fetch == nil {
+			return it.stop(nil)
+		}
+		if err := it.ctx.Err(); err != nil {
+			return it.stop(err)
+		}
+
+		page, err := it.fetch(it.ctx)
+		it.page = page
+		if errors.Is(err, ErrNoMore) {
+			it.fetch = nil
+		} else if err != nil {
+			return it.stop(err)
+		}
+	}
+
+	item := it.page[0]
+	it.page = it.page[1:]
+	if !it.opts.StopAt.IsZero() && it.stamp != nil {
+		if t := it.stamp(item); !t.IsZero() && t.Before(it.opts.StopAt) {
+			return it.stop(nil)
+		}
+	}
+
+	it.item = item
+	it.n++
+	return true
+}
+
+func (it *Iterator[T]) stop(err error) bool {
+	var zero T
+	it.item = zero
+	it.page = nil
+	it.done = true
+	it.err = err
+	return false
+}
+
+// Item returns the current item.
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Error returns the error that ended the iteration, if any. Reaching the end
+// of the feed is not an error.
+func (it *Iterator[T]) Error() error {
+	return it.err
+}
+
+// All returns a range-over-func sequence of the remaining items. Check Error
+// once the loop is done.
+//
+//	for item := range it.All() {
+//	}
+func (it *Iterator[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for it.Next() {
+			if !yield(it.item) {
+				return
+			}
+		}
+	}
+}
+
+// Collect returns the remaining items.
+func (it *Iterator[T]) Collect() ([]T, error) {
+	var items []T
+	for it.Next() {
+		items = append(items, it.item)
+	}
+	return items, it.err
+}
+
+// pageErr returns the error to end the iteration with, after a feed's Next
+// method has been called.
+func pageErr(ok bool, err error) error {
+	if err == nil && !ok {
+		return ErrNoMore
+	}
+	return err
+}
+
+// unixTime converts a timestamp in seconds, returning the zero time if the
+// timestamp is not set.
+func unixTime(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// unixMicroTime is like unixTime, for timestamps in microseconds.
+func unixMicroTime(usec int64) time.Time {
+	if usec == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(usec)
+}
+
+func itemTime(item *Item) time.Time {
+	return unixTime(item.TakenAt)
+}
+
+// Iter returns an iterator over the items of the feed.
+func (media *FeedMedia) Iter(opts ...IterOptions) *Iterator[*Item] {
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if media.err != nil {
+			return nil, media.err
+		}
+		n := len(media.Items)
+		ok := media.NextContext(ctx)
+		return media.Items[n:], pageErr(ok, media.err)
+	}
+	return newIterator(media.insta, media.Items, fetch, itemTime, opts)
+}
+
+// Iter returns an iterator over the users.
+func (users *Users) Iter(opts ...IterOptions) *Iterator[*User] {
+	fetch := func(ctx context.Context) ([]*User, error) {
+		if users.err != nil {
+			return nil, users.err
+		}
+		if !users.NextContext(ctx) {
+			return nil, pageErr(false, users.err)
+		}
+		return users.Users, users.err
+	}
+	return newIterator(users.insta, users.Users, fetch, nil, opts)
+}
+
+// Iter returns an iterator over the comments.
+func (comments *Comments) Iter(opts ...IterOptions) *Iterator[*Comment] {
+	// Sync needs the media the comments belong to, without it the error is
+	// reported by Iterator.Error.
+	var insta *Instagram
+	if comments.item == nil {
+		comments.err = ErrInstaNotDefined
+	} else {
+		if comments.endpoint == "" {
+			comments.Sync()
+		}
+		insta = comments.item.insta
+	}
+	page := func() []*Comment {
+		page := make([]*Comment, len(comments.Items))
+		for i := range comments.Items {
+			page[i] = &comments.Items[i]
+		}
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*Comment, error) {
+		if comments.err != nil {
+			return nil, comments.err
+		}
+		if !comments.Next() {
+			return nil, pageErr(false, comments.err)
+		}
+		return page(), comments.err
+	}
+	stamp := func(c *Comment) time.Time {
+		return unixTime(c.CreatedAt)
+	}
+	return newIterator(insta, page(), fetch, stamp, opts)
+}
+
+// Iter returns an iterator over the posts of the timeline.
+func (tl *Timeline) Iter(opts ...IterOptions) *Iterator[*Item] {
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if tl.err != nil {
+			return nil, tl.err
+		}
+		n := len(tl.Items)
+		ok := tl.Next()
+		return tl.Items[n:], pageErr(ok, tl.err)
+	}
+	return newIterator(tl.insta, tl.Items, fetch, itemTime, opts)
+}
+
+// Iter returns an iterator over the top posts of the hashtag.
+func (h *Hashtag) Iter(opts ...IterOptions) *Iterator[*Item] {
+	return h.iter("top", opts)
+}
+
+// IterRecent returns an iterator over the recent posts of the hashtag.
+func (h *Hashtag) IterRecent(opts ...IterOptions) *Iterator[*Item] {
+	return h.iter("recent", opts)
+}
+
+func (h *Hashtag) iter(tab string, opts []IterOptions) *Iterator[*Item] {
+	items := func() []*Item {
+		if tab == "recent" {
+			return h.ItemsRecent
+		}
+		return h.Items
+	}
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if info, ok := h.PageInfo[tab]; ok && !info.MoreAvailable {
+			return nil, ErrNoMore
+		}
+		n := len(items())
+		if h.next(tab) {
+			// h.err may be left over from the other tab
+			return items()[n:], nil
+		}
+		return items()[n:], pageErr(false, h.err)
+	}
+	return newIterator(h.insta, items(), fetch, itemTime, opts)
+}
+
+// Iter returns an iterator over the posts of the collection.
+func (c *Collection) Iter(opts ...IterOptions) *Iterator[*Item] {
+	page := func(n int) []*Item {
+		page := make([]*Item, 0, len(c.Items)-n)
+		for i := n; i < len(c.Items); i++ {
+			page = append(page, &c.Items[i])
+		}
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if c.err != nil {
+			return nil, c.err
+		}
+		n := len(c.Items)
+		ok := c.Next()
+		return page(n), pageErr(ok, c.err)
+	}
+	return newIterator(c.insta, page(0), fetch, itemTime, opts)
+}
+
+// Iter returns an iterator over the saved posts.
+func (media *SavedMedia) Iter(opts ...IterOptions) *Iterator[*Item] {
+	page := func(n int) []*Item {
+		page := make([]*Item, 0, len(media.Items)-n)
+		for i := n; i < len(media.Items); i++ {
+			page = append(page, &media.Items[i].Media)
+		}
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if media.err != nil {
+			return nil, media.err
+		}
+		n := len(media.Items)
+		ok := media.Next()
+		return page(n), pageErr(ok, media.err)
+	}
+	return newIterator(media.insta, page(0), fetch, itemTime, opts)
+}
+
+// Iter returns an iterator over the videos of the channel.
+func (igtv *IGTVChannel) Iter(opts ...IterOptions) *Iterator[*Item] {
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if igtv.err != nil {
+			return nil, igtv.err
+		}
+		n := len(igtv.Items)
+		ok := igtv.Next()
+		return igtv.Items[n:], pageErr(ok, igtv.err)
+	}
+	return newIterator(igtv.insta, igtv.Items, fetch, itemTime, opts)
+}
+
+// Iter returns an iterator over the conversations in the inbox, the most
+// recently active first.
+func (inbox *Inbox) Iter(opts ...IterOptions) *Iterator[*Conversation] {
+	seen := map[string]bool{}
+	unseen := func() []*Conversation {
+		var page []*Conversation
+		for _, c := range inbox.Conversations {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				page = append(page, c)
+			}
+		}
+		sort.SliceStable(page, func(i, j int) bool {
+			return page[i].LastActivityAt > page[j].LastActivityAt
+		})
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*Conversation, error) {
+		if inbox.err != nil {
+			return nil, inbox.err
+		}
+		ok := inbox.NextContext(ctx)
+		return unseen(), pageErr(ok, inbox.err)
+	}
+	stamp := func(c *Conversation) time.Time {
+		return unixMicroTime(c.LastActivityAt)
+	}
+	return newIterator(inbox.insta, unseen(), fetch, stamp, opts)
+}
+
+// Iter returns an iterator over the messages of the conversation, the newest
+// first.
+func (c *Conversation) Iter(opts ...IterOptions) *Iterator[*InboxItem] {
+	seen := map[string]bool{}
+	unseen := func() []*InboxItem {
+		var page []*InboxItem
+		for _, msg := range c.Items {
+			if !seen[msg.ID] {
+				seen[msg.ID] = true
+				page = append(page, msg)
+			}
+		}
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*InboxItem, error) {
+		if c.err != nil {
+			return nil, c.err
+		}
+		if !c.Next() {
+			return nil, pageErr(false, c.err)
+		}
+		// Conversation.Next doesn't report the end of the conversation
+		page := unseen()
+		if len(page) == 0 || !c.HasOlder {
+			return page, ErrNoMore
+		}
+		return page, nil
+	}
+	stamp := func(msg *InboxItem) time.Time {
+		return unixMicroTime(msg.Timestamp)
+	}
+	return newIterator(c.insta, unseen(), fetch, stamp, opts)
+}
+
+// Iter returns an iterator over the notifications, the new ones first.
+func (act *Activity) Iter(opts ...IterOptions) *Iterator[*RecentItems] {
+	page := func() []*RecentItems {
+		var page []*RecentItems
+		for i := range act.NewStories {
+			page = append(page, &act.NewStories[i])
+		}
+		for i := range act.OldStories {
+			page = append(page, &act.OldStories[i])
+		}
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*RecentItems, error) {
+		if act.err != nil {
+			return nil, act.err
+		}
+		ok := act.Next()
+		if !ok && act.err != ErrNoMore {
+			return nil, pageErr(ok, act.err)
+		}
+		return page(), pageErr(ok, act.err)
+	}
+	stamp := func(r *RecentItems) time.Time {
+		return unixTime(int64(r.Args.Timestamp))
+	}
+	return newIterator(act.insta, page(), fetch, stamp, opts)
+}
+
+// Iter returns an iterator over the posts of the explore page.
+func (disc *Discover) Iter(opts ...IterOptions) *Iterator[*Item] {
+	page := func(n int) []*Item {
+		var page []*Item
+		for _, sec := range disc.Items[n:] {
+			c := sec.LayoutContent
+			for _, m := range slices.Concat(c.Medias, c.FillItems) {
+				page = append(page, &m.Media)
+			}
+			for _, m := range []DiscoverMediaItem{c.OneByOneItem, c.TwoByTwoItem} {
+				if m.Media.ID != nil {
+					page = append(page, &m.Media)
+				}
+			}
+			for _, m := range c.ThreeByFourItem.Clips.Items {
+				page = append(page, &m.Media)
+			}
+		}
+		return page
+	}
+	fetch := func(ctx context.Context) ([]*Item, error) {
+		if disc.err != nil {
+			return nil, disc.err
+		}
+		n := len(disc.Items)
+		ok := disc.Next()
+		if ok && !disc.MoreAvailable {
+			return page(n), ErrNoMore
+		}
+		return page(n), pageErr(ok, disc.err)
+	}
+	return newIterator(disc.insta, page(0), fetch, nil, opts)
+}
+
+// Iter returns an iterator over the results of a top search.
+func (sr *SearchResult) Iter(opts ...IterOptions) *Iterator[*TopSearchItem] {
+	fetch := func(ctx context.Context) ([]*TopSearchItem, error) {
+		if !sr.HasMore || sr.RankToken == "" || sr.PageToken == "" {
+			return nil, ErrNoMore
+		}
+		n := len(sr.Results)
+		ok := sr.Next()
+		return sr.Results[n:], pageErr(ok, sr.err)
+	}
+	return newIterator(sr.insta, sr.Results, fetch, nil, opts)
+}
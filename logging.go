@@ -0,0 +1,123 @@
+package goinsta
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// SetLogger sets the structured logger goinsta logs to. Log records carry
+// attributes such as endpoint, status, user_id, attempt and latency.
+//
+// A logger replaces the info, warn and debug handlers. Requests are logged at
+// debug level, including the response body if Instagram.Debug is set. Pass nil
+// to go back to using the handlers.
+func (insta *Instagram) SetLogger(l *slog.Logger) {
+	insta.logger = l
+}
+
+// Logger returns the logger goinsta logs to. If no logger has been set with
+// SetLogger, it returns a logger that passes records on to the info, warn and
+// debug handlers.
+func (insta *Instagram) Logger() *slog.Logger {
+	if insta.logger != nil {
+		return insta.logger
+	}
+	return slog.New(&handlerAdapter{insta: insta})
+}
+
+// log returns the logger, with the ID of the logged in user attached.
+func (insta *Instagram) log() *slog.Logger {
+	l := insta.Logger()
+	if insta.Account != nil && insta.Account.ID != 0 {
+		l = l.With(slog.Int64("user_id", insta.Account.ID))
+	}
+	return l
+}
+
+// handlerAdapter is a slog.Handler that formats records as a single line, and
+// passes them on to the handlers set with SetInfoHandler, SetWarnHandler and
+// SetDebugHandler.
+type handlerAdapter struct {
+	insta  *Instagram
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *handlerAdapter) Enabled(_ context.Context, level slog.Level) bool {
+	return level > slog.LevelDebug || h.insta.Debug
+}
+
+func (h *handlerAdapter) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, a := range h.attrs {
+		writeAttr(&b, "", a)
+	}
+	prefix := strings.Join(h.groups, ".")
+	r.Attrs(func(a slog.Attr) bool {
+		writeAttr(&b, prefix, a)
+		return true
+	})
+
+	var handler func(...interface{})
+	switch {
+	case r.Level >= slog.LevelWarn:
+		handler = h.insta.warnHandler
+	case r.Level >= slog.LevelInfo:
+		handler = h.insta.infoHandler
+	default:
+		handler = h.insta.debugHandler
+	}
+	if handler != nil {
+		handler(b.String())
+	}
+	return nil
+}
+
+func (h *handlerAdapter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]slog.Attr{}, h.attrs...)
+	prefix := strings.Join(h.groups, ".")
+	for _, a := range attrs {
+		if prefix != "" {
+			a.Key = prefix + "." + a.Key
+		}
+		c.attrs = append(c.attrs, a)
+	}
+	return &c
+}
+
+func (h *handlerAdapter) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = append(append([]string{}, h.groups...), name)
+	return &c
+}
+
+func writeAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if prefix != "" {
+		key = prefix + "." + key
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, g := range a.Value.Group() {
+			writeAttr(b, key, g)
+		}
+		return
+	}
+
+	v := a.Value.String()
+	if strings.ContainsAny(v, " \t\n\"=") {
+		v = fmt.Sprintf("%q", v)
+	}
+	fmt.Fprintf(b, " %s=%s", key, v)
+}
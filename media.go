@@ -2,6 +2,7 @@ package goinsta
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -264,6 +265,11 @@ func (item *Item) Comment(text string) error {
 		return item.Reply(text)
 	}
 
+	// Don't bother checking the comment if commenting has been blocked
+	if err := item.insta.checkCooldown(ActionComment); err != nil {
+		return err
+	}
+
 	o, err := item.CommentCheckOffensive(text)
 	if err != nil {
 		return err
@@ -313,6 +319,7 @@ func (item *Item) comment(text string) error {
 			Endpoint: fmt.Sprintf(urlCommentAdd, item.Pk),
 			Query:    map[string]string{"signed_body": "SIGNATURE." + string(b)},
 			IsPost:   true,
+			Action:   ActionComment,
 		},
 	)
 	return err
@@ -644,6 +651,7 @@ func (item *Item) changeLike(endpoint string) error {
 				"d":           "0",
 			},
 			IsPost: true,
+			Action: ActionLike,
 		},
 	)
 	return err
@@ -681,12 +689,9 @@ func (item *Item) DownloadTo(dst string) error {
 		return item.downloadCarousel(folder, file)
 	}
 
-	insta.warnHandler(
-		fmt.Sprintf(
-			"Unable to download %s media (media type %d), this has not been implemented",
-			item.MediaToString(),
-			item.MediaType,
-		),
+	insta.log().Warn("Unable to download media, this has not been implemented",
+		"media", item.MediaToString(),
+		"media_type", item.MediaType,
 	)
 	return ErrNoMedia
 
@@ -971,6 +976,12 @@ func (media *FeedMedia) GetNextID() string {
 // returns false when list reach the end.
 // if FeedMedia.Error() is ErrNoMore no problems have occurred.
 func (media *FeedMedia) Next(params ...interface{}) bool {
+	return media.NextContext(media.insta.Context(), params...)
+}
+
+// NextContext is like Next, but the request will be aborted when the context
+// is cancelled.
+func (media *FeedMedia) NextContext(ctx context.Context, params ...interface{}) bool {
 	if media.err != nil {
 		return false
 	}
@@ -997,6 +1008,7 @@ func (media *FeedMedia) Next(params ...interface{}) bool {
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  ctx,
 			Endpoint: endpoint,
 			Query:    query,
 		},
@@ -1023,6 +1035,7 @@ func (media *FeedMedia) Next(params ...interface{}) bool {
 			return true
 		}
 	}
+	media.err = err
 	return false
 }
 
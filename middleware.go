@@ -0,0 +1,104 @@
+package goinsta
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware hooks into every request goinsta makes. All hooks are optional.
+// Middleware is added with Use, and runs in the order it has been added, so
+// e.g. metrics, auditing and rate limiting can be stacked independently.
+//
+// For every request, the BeforeSend hooks are called just before it is sent,
+// and the AfterReceive hooks once the response has been read, or sending has
+// failed or been aborted. If the request failed, the OnError handlers are called, followed by
+// the wrapper set with SetWrapper. The default wrapper takes care of 2FA,
+// checkpoints, challenges and retries, so middleware doesn't have to.
+type Middleware struct {
+	// BeforeSend is called before the request is sent. It can modify the
+	// request, e.g. to set headers or query parameters. If it returns an
+	// error, the request is not sent, and the error is returned.
+	BeforeSend func(r *Request) error
+
+	// AfterReceive is called after a response has been received. It can
+	// modify the response body and error.
+	AfterReceive func(r *Response)
+
+	// OnError is called if the request failed. It can handle the error by
+	// returning a nil error, or retry the request with args.RetryRequest.
+	// The remaining error handlers, and the wrapper, are skipped once the
+	// error has been handled, or the request has been retried.
+	OnError func(args *ReqWrapperArgs) (body []byte, h http.Header, err error)
+}
+
+// Request is a request about to be sent.
+type Request struct {
+	*http.Request
+
+	// Endpoint is the API endpoint, e.g. feed/timeline/
+	Endpoint string
+
+	// Attempt is the number of times the request has been sent before, plus one
+	Attempt int
+}
+
+// Response is the response to a request.
+type Response struct {
+	Request *Request
+
+	// StatusCode is 0 if no response has been received
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// Latency is the time it took to receive the response
+	Latency time.Duration
+
+	// Error is the error returned by the request, if any
+	Error error
+}
+
+// Use adds middleware to the request chain, see Middleware.
+func (insta *Instagram) Use(m ...Middleware) {
+	insta.middleware = append(insta.middleware, m...)
+}
+
+func (insta *Instagram) beforeSend(r *Request) error {
+	for _, m := range insta.middleware {
+		if m.BeforeSend == nil {
+			continue
+		}
+		if err := m.BeforeSend(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (insta *Instagram) afterReceive(r *Response) {
+	for _, m := range insta.middleware {
+		if m.AfterReceive != nil {
+			m.AfterReceive(r)
+		}
+	}
+}
+
+// handleError passes the outcome of a request through the error handlers of
+// the middleware, and the request wrapper.
+func (insta *Instagram) handleError(args *ReqWrapperArgs) ([]byte, http.Header, error) {
+	failed := args.Error != nil
+	for _, m := range insta.middleware {
+		if args.Error == nil || args.retried {
+			break
+		}
+		if m.OnError != nil {
+			args.Body, args.Headers, args.Error = m.OnError(args)
+		}
+	}
+
+	handled := failed && args.Error == nil
+	if insta.reqWrapper == nil || handled || args.retried {
+		return args.Body, args.Headers, args.Error
+	}
+	return insta.reqWrapper.GoInstaWrapper(args)
+}
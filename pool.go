@@ -0,0 +1,371 @@
+package goinsta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AccountState is the health of an account in an AccountPool.
+type AccountState string
+
+const (
+	// AccountHealthy accounts can be leased
+	AccountHealthy AccountState = "healthy"
+	// AccountRateLimited accounts can be leased again after a while
+	AccountRateLimited AccountState = "rate_limited"
+	// AccountActionBlocked accounts can be leased again once the cooldown of
+	// the blocked action has expired, see Instagram.Cooldown
+	AccountActionBlocked AccountState = "action_blocked"
+	// AccountChallenged accounts need a challenge to be solved, and won't be
+	// leased until reset with SetState
+	AccountChallenged AccountState = "challenged"
+	// AccountLoggedOut accounts need to login again, and won't be leased
+	// until reset with SetState
+	AccountLoggedOut AccountState = "logged_out"
+)
+
+// ErrNoHealthyAccounts is returned by AccountPool.Lease if none of the accounts
+// in the pool can be leased, now or in the future.
+var ErrNoHealthyAccounts = errors.New("no healthy accounts left in the pool")
+
+// AccountStatus is the state of an account in an AccountPool.
+type AccountStatus struct {
+	Username string       `json:"username"`
+	State    AccountState `json:"state"`
+
+	// Until is the time a rate limited or blocked account can be leased again,
+	// or the zero time if it is not limited
+	Until time.Time `json:"until"`
+
+	// LastError is the error that caused the account to become unhealthy
+	LastError string `json:"last_error,omitempty"`
+
+	LastUsed time.Time `json:"last_used"`
+	Leases   int       `json:"leases"`
+	Leased   bool      `json:"-"`
+}
+
+// AccountPool owns many sessions, and leases them out to workers one at a
+// time. It tracks the health of every account by watching the responses to
+// its requests, and skips accounts that are logged out, challenged, rate
+// limited or blocked.
+//
+//	pool := goinsta.NewAccountPool(accounts...)
+//	lease, err := pool.Lease(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer lease.Release()
+//	user, err := lease.Insta.Profiles.ByName("instagram")
+type AccountPool struct {
+	// RateLimitCooldown is how long a rate limited account is skipped, if
+	// Instagram didn't say how long to wait. Defaults to TooManyRequestsTimeout.
+	RateLimitCooldown time.Duration
+
+	mu       sync.Mutex
+	accounts []*poolAccount
+	next     int
+
+	// Closed, and replaced, whenever an account may have become available
+	wake chan struct{}
+}
+
+type poolAccount struct {
+	insta  *Instagram
+	status AccountStatus
+}
+
+// Lease is an account leased from an AccountPool. Call Release when done.
+type Lease struct {
+	Insta *Instagram
+
+	pool *AccountPool
+	acc  *poolAccount
+	once sync.Once
+}
+
+// NewAccountPool creates a pool of the accounts provided.
+func NewAccountPool(accounts ...*Instagram) *AccountPool {
+	p := &AccountPool{
+		RateLimitCooldown: TooManyRequestsTimeout,
+		wake:              make(chan struct{}),
+	}
+	for _, insta := range accounts {
+		p.Add(insta)
+	}
+	return p
+}
+
+// Add adds an account to the pool.
+func (p *AccountPool) Add(insta *Instagram) {
+	p.add(insta, AccountStatus{Username: insta.user, State: AccountHealthy})
+}
+
+func (p *AccountPool) add(insta *Instagram, status AccountStatus) {
+	acc := &poolAccount{insta: insta, status: status}
+	insta.Use(Middleware{
+		AfterReceive: func(r *Response) {
+			p.observe(acc, r.Error)
+		},
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.accounts = append(p.accounts, acc)
+	p.notify()
+}
+
+// Lease returns an account that is not leased, and is healthy. If no account
+// is available yet, it waits until one is, or the context is cancelled. If no
+// account will become available, ErrNoHealthyAccounts is returned.
+//
+// Accounts are leased in turn, to spread the load.
+func (p *AccountPool) Lease(ctx context.Context) (*Lease, error) {
+	for {
+		p.mu.Lock()
+		acc, wait, ok := p.pick()
+		if acc != nil {
+			acc.status.Leased = true
+			acc.status.Leases++
+			acc.status.LastUsed = time.Now()
+			p.mu.Unlock()
+			return &Lease{Insta: acc.insta, pool: p, acc: acc}, nil
+		}
+		wake := p.wake
+		p.mu.Unlock()
+
+		if !ok {
+			return nil, ErrNoHealthyAccounts
+		}
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-wake:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// pick returns the next account to lease. If none can be leased right now,
+// it returns how long until a rate limited or blocked account becomes
+// available, if any, and whether any account may become available at all.
+func (p *AccountPool) pick() (acc *poolAccount, wait time.Duration, ok bool) {
+	now := time.Now()
+	for i := range p.accounts {
+		a := p.accounts[(p.next+i)%len(p.accounts)]
+		s := &a.status
+
+		switch s.State {
+		case AccountChallenged, AccountLoggedOut:
+			continue
+		case AccountRateLimited, AccountActionBlocked:
+			if now.Before(s.Until) {
+				if d := s.Until.Sub(now); wait == 0 || d < wait {
+					wait = d
+				}
+				ok = true
+				continue
+			}
+			s.State, s.Until, s.LastError = AccountHealthy, time.Time{}, ""
+		}
+
+		ok = true
+		if !s.Leased {
+			p.next = (p.next + i + 1) % len(p.accounts)
+			return a, 0, true
+		}
+	}
+	return nil, wait, ok
+}
+
+// Release returns the account to the pool. It is safe to call Release more
+// than once.
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		l.pool.mu.Lock()
+		defer l.pool.mu.Unlock()
+		l.acc.status.Leased = false
+		l.pool.notify()
+	})
+}
+
+// observe updates the state of an account, based on the outcome of a request.
+func (p *AccountPool) observe(acc *poolAccount, err error) {
+	var (
+		state AccountState
+		until time.Time
+	)
+
+	var rl RateLimitError
+	switch {
+	case err == nil:
+		state = AccountHealthy
+	case errors.Is(err, ErrLoggedOut), errors.Is(err, ErrLoginRequired):
+		state = AccountLoggedOut
+	case errors.Is(err, ErrChallengeRequired), errors.Is(err, ErrCheckpointRequired):
+		state = AccountChallenged
+	case errors.Is(err, ErrFeedbackRequired):
+		state = AccountActionBlocked
+		until = acc.insta.latestCooldown()
+	case errors.As(err, &rl), errors.Is(err, ErrTooManyRequests):
+		state = AccountRateLimited
+		d := rl.RetryAfter
+		if d == 0 {
+			d = p.RateLimitCooldown
+		}
+		until = time.Now().Add(d)
+	default:
+		// Other errors don't say anything about the account
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := &acc.status
+	if state == AccountHealthy {
+		// A successful request doesn't lift a block on other actions
+		if s.State != AccountActionBlocked || time.Now().After(s.Until) {
+			s.State, s.Until, s.LastError = AccountHealthy, time.Time{}, ""
+		}
+		return
+	}
+	s.State, s.Until, s.LastError = state, until, err.Error()
+}
+
+// notify wakes up goroutines waiting for an account. Must be called with the
+// lock held.
+func (p *AccountPool) notify() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+// Accounts returns the status of all accounts in the pool.
+func (p *AccountPool) Accounts() []AccountStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]AccountStatus, 0, len(p.accounts))
+	for _, a := range p.accounts {
+		statuses = append(statuses, a.status)
+	}
+	return statuses
+}
+
+// SetState sets the state of an account, e.g. to put it back in rotation
+// after solving a challenge, or logging in again. It returns false if there is
+// no account with the username provided.
+func (p *AccountPool) SetState(username string, state AccountState) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, a := range p.accounts {
+		if a.status.Username == username {
+			a.status.State, a.status.Until, a.status.LastError = state, time.Time{}, ""
+			p.notify()
+			return true
+		}
+	}
+	return false
+}
+
+// poolFile is the format an AccountPool is exported in.
+type poolFile struct {
+	Accounts []poolFileAccount `json:"accounts"`
+}
+
+type poolFileAccount struct {
+	Config ConfigFile    `json:"config"`
+	Status AccountStatus `json:"status"`
+}
+
+// Export saves the sessions, and state, of all accounts to path.
+func (p *AccountPool) Export(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := p.ExportIO(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ExportIO writes the sessions, and state, of all accounts to writer.
+func (p *AccountPool) ExportIO(writer io.Writer) error {
+	p.mu.Lock()
+	accounts := append([]*poolAccount{}, p.accounts...)
+	p.mu.Unlock()
+
+	var file poolFile
+	for _, a := range accounts {
+		p.mu.Lock()
+		status := a.status
+		p.mu.Unlock()
+
+		file.Accounts = append(file.Accounts, poolFileAccount{
+			Config: a.insta.ExportConfig(),
+			Status: status,
+		})
+	}
+	return json.NewEncoder(writer).Encode(file)
+}
+
+// ImportAccountPool loads a pool exported with AccountPool.Export. No requests
+// are made to sync the accounts.
+func ImportAccountPool(path string) (*AccountPool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ImportAccountPoolReader(f)
+}
+
+// ImportAccountPoolReader loads a pool exported with AccountPool.ExportIO. No
+// requests are made to sync the accounts.
+func ImportAccountPoolReader(r io.Reader) (*AccountPool, error) {
+	var file poolFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	p := NewAccountPool()
+	for _, a := range file.Accounts {
+		insta, err := ImportConfig(a.Config, true)
+		if err != nil {
+			return nil, err
+		}
+		a.Status.Leased = false
+		p.add(insta, a.Status)
+	}
+	return p, nil
+}
+
+// EnvLoadPool loads all accounts from the environment, like EnvLoadAccs, into
+// a pool.
+func EnvLoadPool(p ...string) (*AccountPool, error) {
+	accounts, err := EnvLoadAccs(p...)
+	if err != nil {
+		return nil, err
+	}
+	return NewAccountPool(accounts...), nil
+}
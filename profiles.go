@@ -1,9 +1,12 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -130,7 +133,7 @@ func (user *User) VisitProfile() (*Profile, error) {
 		defer wg.Done()
 		_, err := user.GetFeaturedAccounts()
 		if err != nil {
-			user.insta.warnHandler(err)
+			user.insta.log().Warn("Failed to fetch featured accounts", "error", err)
 		}
 	}(wg)
 
@@ -170,17 +173,11 @@ func newProfiles(insta *Instagram) *Profiles {
 //   or insta.Searchbar.SearchUser(user).
 //
 func (prof *Profiles) ByName(name string) (*User, error) {
-	body, err := prof.insta.sendSimpleRequest(urlUserByName, name)
-	if err == nil {
-		resp := userResp{}
-		err = json.Unmarshal(body, &resp)
-		if err == nil {
-			user := &resp.User
-			user.insta = prof.insta
-			return user, err
-		}
-	}
-	return nil, err
+	return prof.byName(prof.insta.Context(), name)
+}
+
+func (prof *Profiles) byName(ctx context.Context, name string) (*User, error) {
+	return prof.user(ctx, fmt.Sprintf(urlUserByName, name))
 }
 
 // ByID returns a *User structure parsed by user id.
@@ -196,10 +193,18 @@ func (prof *Profiles) ByID(id_ interface{}) (*User, error) {
 	default:
 		return nil, errors.New("invalid id, please provide a string or int(64)")
 	}
+	return prof.byID(prof.insta.Context(), id)
+}
 
+func (prof *Profiles) byID(ctx context.Context, id string) (*User, error) {
+	return prof.user(ctx, fmt.Sprintf(urlUserByID, id))
+}
+
+func (prof *Profiles) user(ctx context.Context, endpoint string) (*User, error) {
 	body, _, err := prof.insta.sendRequest(
 		&reqOptions{
-			Endpoint: fmt.Sprintf(urlUserByID, id),
+			Context:  ctx,
+			Endpoint: endpoint,
 		},
 	)
 	if err == nil {
@@ -214,6 +219,102 @@ func (prof *Profiles) ByID(id_ interface{}) (*User, error) {
 	return nil, err
 }
 
+// defaultBatchWorkers is the number of concurrent requests of batch lookups,
+// if not configured.
+const defaultBatchWorkers = 4
+
+// BatchOptions configures batch lookups, like Profiles.ByIDs.
+type BatchOptions struct {
+	// Workers is the maximum number of concurrent requests. Defaults to 4.
+	// Requests still pass the rate limiter, see SetRateLimiter, so more
+	// workers only help as far as the limiter allows.
+	Workers int
+
+	// Context aborts the lookups when cancelled. Lookups that haven't
+	// started yet fail with the context error. Defaults to the context of the
+	// Instagram instance, see SetContext.
+	Context context.Context
+}
+
+// UserResult is the result of looking up a single user in a batch.
+type UserResult struct {
+	// Key is the user ID or username that has been looked up
+	Key string
+
+	User *User
+	Err  error
+}
+
+// ByIDs looks up many users by ID concurrently, with at most
+// BatchOptions.Workers requests in flight. Duplicate IDs are looked up once.
+// A result is returned for each distinct ID, in the order of ids. Lookups
+// that fail don't abort the batch, check UserResult.Err.
+func (prof *Profiles) ByIDs(ids []int64, opts ...BatchOptions) []UserResult {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = strconv.FormatInt(id, 10)
+	}
+	return prof.batch(keys, func(key string) string { return key }, prof.byID, opts)
+}
+
+// ByNames looks up many users by username concurrently, like ByIDs.
+// Usernames are case insensitive, so names that only differ in case are
+// looked up once.
+func (prof *Profiles) ByNames(names []string, opts ...BatchOptions) []UserResult {
+	return prof.batch(names, strings.ToLower, prof.byName, opts)
+}
+
+func (prof *Profiles) batch(
+	keys []string,
+	normalize func(string) string,
+	lookup func(context.Context, string) (*User, error),
+	opts []BatchOptions,
+) []UserResult {
+	var o BatchOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Workers <= 0 {
+		o.Workers = defaultBatchWorkers
+	}
+	ctx := o.Context
+	if ctx == nil {
+		ctx = prof.insta.Context()
+	}
+
+	seen := make(map[string]bool, len(keys))
+	results := make([]UserResult, 0, len(keys))
+	for _, key := range keys {
+		if n := normalize(key); !seen[n] {
+			seen[n] = true
+			results = append(results, UserResult{Key: key})
+		}
+	}
+
+	jobs := make(chan *UserResult)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < o.Workers && i < len(results); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				if err := ctx.Err(); err != nil {
+					r.Err = err
+					continue
+				}
+				r.User, r.Err = lookup(ctx, r.Key)
+			}
+		}()
+	}
+	for i := range results {
+		jobs <- &results[i]
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
 // Blocked returns a list of users you have blocked.
 func (prof *Profiles) Blocked() ([]BlockedUser, error) {
 	body, err := prof.insta.sendSimpleRequest(urlBlockedList)
@@ -0,0 +1,156 @@
+package goinsta
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is consulted before every request made by goinsta. Wait should
+// block until the request to the endpoint is allowed to be made, or return an
+// error if the context is cancelled before that.
+//
+// A rate limiter can be set with Instagram.SetRateLimiter.
+type RateLimiter interface {
+	Wait(ctx context.Context, endpoint string) error
+}
+
+// EndpointLimit is a request budget for a group of endpoints.
+type EndpointLimit struct {
+	// Pattern the endpoint is matched against, e.g. "friendships/create/*",
+	// "media/*/like" or "rupload_*". A * matches any sequence of characters,
+	// trailing slashes and query parameters are ignored.
+	Pattern string
+
+	// Number of requests allowed per Interval
+	Requests int
+	Interval time.Duration
+
+	// Burst is the maximum number of requests that can be made at once.
+	// Defaults to Requests.
+	Burst int
+}
+
+// DefaultRateLimits are the budgets used by NewTokenBucketLimiter when no
+// limits are provided. They aim to stay below Instagram's action limits.
+var DefaultRateLimits = []EndpointLimit{
+	{Pattern: "friendships/create/*", Requests: 60, Interval: time.Hour, Burst: 5},
+	{Pattern: "friendships/destroy/*", Requests: 60, Interval: time.Hour, Burst: 5},
+	{Pattern: "friendships/*", Requests: 200, Interval: time.Hour, Burst: 20},
+	{Pattern: "media/*/like", Requests: 60, Interval: time.Hour, Burst: 5},
+	{Pattern: "media/*/unlike", Requests: 60, Interval: time.Hour, Burst: 5},
+	{Pattern: "media/*/comment", Requests: 30, Interval: time.Hour, Burst: 3},
+	{Pattern: "direct_v2/threads/broadcast/*", Requests: 60, Interval: time.Hour, Burst: 5},
+	{Pattern: "direct_v2/*", Requests: 300, Interval: time.Hour, Burst: 20},
+	{Pattern: "rupload_*", Requests: 120, Interval: time.Minute, Burst: 30},
+}
+
+// TokenBucketLimiter is a RateLimiter with a token bucket per endpoint group.
+// Endpoints are matched against the groups in order, the first match is used.
+// Requests to endpoints that don't match any group are not limited.
+type TokenBucketLimiter struct {
+	buckets []*tokenBucket
+}
+
+type tokenBucket struct {
+	mu sync.Mutex
+
+	limit  EndpointLimit
+	rate   float64 // tokens per second
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketLimiter creates a new rate limiter with the limits provided,
+// if none are provided DefaultRateLimits will be used.
+func NewTokenBucketLimiter(limits ...EndpointLimit) *TokenBucketLimiter {
+	if len(limits) == 0 {
+		limits = DefaultRateLimits
+	}
+
+	l := &TokenBucketLimiter{}
+	for _, limit := range limits {
+		if limit.Requests <= 0 || limit.Interval <= 0 {
+			continue
+		}
+		if limit.Burst <= 0 {
+			limit.Burst = limit.Requests
+		}
+		l.buckets = append(l.buckets, &tokenBucket{
+			limit:  limit,
+			rate:   float64(limit.Requests) / limit.Interval.Seconds(),
+			tokens: float64(limit.Burst),
+			last:   time.Now(),
+		})
+	}
+	return l
+}
+
+// Wait blocks until a token is available in the bucket matching the endpoint.
+func (l *TokenBucketLimiter) Wait(ctx context.Context, endpoint string) error {
+	for _, b := range l.buckets {
+		if matchEndpoint(b.limit.Pattern, endpoint) {
+			return b.wait(ctx)
+		}
+	}
+	return nil
+}
+
+func (b *tokenBucket) wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if max := float64(b.limit.Burst); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+
+	// Reserve a token, this can make the bucket go negative, which will
+	// make the next caller wait longer.
+	b.tokens--
+	if b.tokens >= 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	d := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+
+	if err := sleepContext(ctx, d); err != nil {
+		// Give the token back, as no request will be made
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// matchEndpoint reports whether endpoint matches the pattern, where * matches
+// any sequence of characters.
+func matchEndpoint(pattern, endpoint string) bool {
+	if i := strings.Index(endpoint, "?"); i != -1 {
+		endpoint = endpoint[:i]
+	}
+	pattern = strings.Trim(pattern, "/")
+	endpoint = strings.Trim(endpoint, "/")
+
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == endpoint
+	}
+	if !strings.HasPrefix(endpoint, parts[0]) {
+		return false
+	}
+	endpoint = endpoint[len(parts[0]):]
+
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		i := strings.Index(endpoint, part)
+		if i == -1 {
+			return false
+		}
+		endpoint = endpoint[i+len(part):]
+	}
+	return strings.HasSuffix(endpoint, parts[last])
+}
@@ -0,0 +1,443 @@
+package goinsta
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// RecorderMode sets whether a Recorder records or replays requests.
+type RecorderMode int
+
+const (
+	// RecorderRecord passes all requests on to Instagram, and records them.
+	RecorderRecord RecorderMode = iota
+	// RecorderReplay serves previously recorded responses, and never makes
+	// any requests.
+	RecorderReplay
+)
+
+// Recorder is a http.RoundTripper that records request/response pairs to a
+// cassette file on disk, and can replay them later on, e.g. for offline
+// testing. Use Instagram.SetHTTPTransport to plug it in.
+//
+// Authorization headers, cookies, encrypted passwords and secret response
+// fields, such as TOTP seeds and backup codes, are scrubbed before they are
+// written to disk. Use ScrubFields to scrub more response fields.
+type Recorder struct {
+	mode      RecorderMode
+	path      string
+	transport http.RoundTripper
+	fields    []string
+
+	mu           sync.Mutex
+	interactions []*interaction
+	used         []bool
+}
+
+type cassette struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+type interaction struct {
+	Request  recordedRequest  `json:"request"`
+	Response recordedResponse `json:"response"`
+}
+
+// Bodies are stored as text to keep the cassette readable, binary bodies
+// such as media don't survive that, and are stored base64 encoded instead.
+type recordedRequest struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+	BinaryBody []byte      `json:"binary_body,omitempty"`
+}
+
+type recordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+	BinaryBody []byte      `json:"binary_body,omitempty"`
+}
+
+var (
+	// Headers containing secrets, that will be scrubbed
+	recorderScrubHeaders = []string{
+		"Authorization",
+		"Ig-Set-Authorization",
+		"Cookie",
+		"Set-Cookie",
+	}
+	// Passwords as encrypted by utilities.EncryptPassword, both plain and URL encoded
+	recorderPasswordRe = regexp.MustCompile(`(#|%23)PWD_INSTAGRAM(:|%3A)\d+(:|%3A)\d+(:|%3A)[A-Za-z0-9+/=%]+`)
+	// Query parameters containing API keys of third parties
+	recorderScrubParams = []string{"key", "access_token"}
+	// Fields of JSON responses containing secrets
+	recorderScrubFields = []string{"totp_seed", "backup_codes"}
+	// IDs, timestamps and hex encoded UUIDs in paths
+	recorderIDRe = regexp.MustCompile(`[0-9a-f]{32}|\d+`)
+)
+
+// NewRecorder creates a new recorder for the cassette file at path.
+//
+// In replay mode the cassette is loaded from path. In record mode requests are
+// sent with transport, or a default transport if nil, and Save needs to be
+// called to write the cassette to disk.
+func NewRecorder(path string, mode RecorderMode, transport http.RoundTripper) (*Recorder, error) {
+	r := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: transport,
+		fields:    append([]string(nil), recorderScrubFields...),
+	}
+	if r.transport == nil {
+		r.transport = &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+		}
+	}
+
+	if mode == RecorderReplay {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var c cassette
+		if err := json.Unmarshal(b, &c); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		r.interactions = c.Interactions
+		r.used = make([]bool, len(c.Interactions))
+	}
+	return r, nil
+}
+
+// Mode returns the mode the recorder is in.
+func (r *Recorder) Mode() RecorderMode {
+	return r.mode
+}
+
+// ScrubFields adds fields of JSON responses to scrub, on top of the TOTP seeds
+// and backup codes scrubbed by default. Fields are matched by name at any
+// depth.
+func (r *Recorder) ScrubFields(names ...string) {
+	r.mu.Lock()
+	r.fields = append(r.fields, names...)
+	r.mu.Unlock()
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == RecorderReplay {
+		return r.replay(req)
+	}
+	return r.record(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		reqBody = b
+	}
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		b, err := gunzip(reqBody)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// Store and pass on decompressed responses, to keep the cassette readable
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		respBody, err = gunzip(respBody)
+		if err != nil {
+			return nil, err
+		}
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Set("Content-Length", strconv.Itoa(len(respBody)))
+		resp.ContentLength = int64(len(respBody))
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	i := &interaction{
+		Request: recordedRequest{
+			Method: req.Method,
+			URL:    scrubURL(req.URL),
+			Header: scrubHeader(req.Header),
+		},
+		Response: recordedResponse{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Header:     scrubHeader(resp.Header),
+		},
+	}
+	if utf8.Valid(reqBody) {
+		i.Request.Body = scrubBody(string(reqBody))
+	} else {
+		i.Request.BinaryBody = reqBody
+	}
+	if utf8.Valid(respBody) {
+		r.mu.Lock()
+		fields := r.fields
+		r.mu.Unlock()
+		i.Response.Body = string(scrubJSON(respBody, fields))
+	} else {
+		i.Response.BinaryBody = respBody
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, i)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay serves the first unused interaction with the same method, host and
+// path as the request. If there is none, it falls back to the first one with
+// the same path, ignoring IDs and timestamps, as these tend to differ between
+// runs, e.g. for upload IDs.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.match(req, sameEndpoint)
+	if n == -1 {
+		n = r.match(req, sameEndpointShape)
+	}
+	if n == -1 {
+		return nil, fmt.Errorf("no recorded interaction left for %s %s in cassette %s", req.Method, req.URL, r.path)
+	}
+	i := r.interactions[n]
+	r.used[n] = true
+
+	body := []byte(i.Response.Body)
+	if i.Response.BinaryBody != nil {
+		body = i.Response.BinaryBody
+	}
+	h := i.Response.Header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	return &http.Response{
+		StatusCode:    i.Response.StatusCode,
+		Status:        i.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Save writes all recorded interactions to the cassette file. In replay mode
+// this is a no-op.
+func (r *Recorder) Save() error {
+	if r.mode == RecorderReplay {
+		return nil
+	}
+
+	r.mu.Lock()
+	b, err := json.MarshalIndent(cassette{Interactions: r.interactions}, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, b, 0o644)
+}
+
+// Unused returns the number of recorded interactions that have not been
+// replayed yet.
+func (r *Recorder) Unused() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var c int
+	for _, u := range r.used {
+		if !u {
+			c++
+		}
+	}
+	return c
+}
+
+func (r *Recorder) match(req *http.Request, same func(a, b string) bool) int {
+	for n, i := range r.interactions {
+		if r.used[n] || i.Request.Method != req.Method {
+			continue
+		}
+		if same(i.Request.URL, req.URL.String()) {
+			return n
+		}
+	}
+	return -1
+}
+
+func sameEndpoint(a, b string) bool {
+	ua, err := neturl.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := neturl.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host == ub.Host && ua.Path == ub.Path
+}
+
+func sameEndpointShape(a, b string) bool {
+	ua, err := neturl.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := neturl.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host == ub.Host &&
+		recorderIDRe.ReplaceAllString(ua.Path, "0") == recorderIDRe.ReplaceAllString(ub.Path, "0")
+}
+
+func scrubHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range recorderScrubHeaders {
+		if v := h.Values(k); len(v) > 0 {
+			for i := range v {
+				v[i] = scrubSecret(v[i])
+			}
+			h[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	return h
+}
+
+// scrubSecret replaces the secret part of a value. The prefix of values with
+// a colon separated format, such as 'Bearer IGT:2:<token>', is kept intact.
+func scrubSecret(v string) string {
+	if i := strings.LastIndex(v, ":"); i != -1 {
+		return v[:i+1] + "REDACTED"
+	}
+	return "REDACTED"
+}
+
+func scrubURL(u *neturl.URL) string {
+	q := u.Query()
+	var scrubbed bool
+	for _, k := range recorderScrubParams {
+		if q.Has(k) {
+			q.Set(k, "REDACTED")
+			scrubbed = true
+		}
+	}
+	if !scrubbed {
+		return u.String()
+	}
+	c := *u
+	c.RawQuery = q.Encode()
+	return c.String()
+}
+
+func scrubBody(body string) string {
+	return recorderPasswordRe.ReplaceAllString(body, "${1}PWD_INSTAGRAM${2}0${3}0${4}REDACTED")
+}
+
+// scrubJSON replaces the values of fields in a JSON body. Bodies that are not
+// JSON, or contain none of the fields, are returned as is.
+func scrubJSON(body []byte, fields []string) []byte {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return body
+	}
+	if !scrubValue(v, fields) {
+		return body
+	}
+
+	buf := new(bytes.Buffer)
+	e := json.NewEncoder(buf)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// scrubValue scrubs fields in v recursively, and reports whether any were
+// found. Strings, also in lists, are replaced by REDACTED, other values by
+// null.
+func scrubValue(v interface{}, fields []string) bool {
+	var scrubbed bool
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if !slices.Contains(fields, k) {
+				scrubbed = scrubValue(val, fields) || scrubbed
+				continue
+			}
+			v[k] = scrubField(val)
+			scrubbed = true
+		}
+	case []interface{}:
+		for _, val := range v {
+			scrubbed = scrubValue(val, fields) || scrubbed
+		}
+	}
+	return scrubbed
+}
+
+func scrubField(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return "REDACTED"
+	case []interface{}:
+		for i := range v {
+			v[i] = scrubField(v[i])
+		}
+		return v
+	}
+	return nil
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
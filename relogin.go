@@ -0,0 +1,102 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SetAutoRelogin enables logging in again automatically when the session
+// expires, i.e. Instagram responds with login_required. The request that
+// failed is sent once more after logging in, and the refreshed session is
+// saved to the session store, if set with SetSessionStore.
+//
+// The password is kept in memory to be able to login again. If two factor
+// authentication is enabled for the account, set the TOTP seed with
+// SetTOTPSeed, so codes can be generated. Pass an empty password to disable.
+func (insta *Instagram) SetAutoRelogin(password string) {
+	insta.reloginMu.Lock()
+	defer insta.reloginMu.Unlock()
+	insta.reloginPass = password
+}
+
+// Validate checks whether the session is still valid, with a single request
+// to accounts/current_user/, and refreshes Account. Unlike OpenApp, no other
+// requests are made, so it is cheap to call e.g. after importing a session.
+//
+// If auto relogin is enabled, an expired session is refreshed.
+func (insta *Instagram) Validate() error {
+	if insta.Account == nil {
+		return ErrLoginRequired
+	}
+	return insta.Account.Sync()
+}
+
+// authorization returns the current authorization header.
+func (insta *Instagram) authorization() string {
+	if auth, ok := insta.headerOptions.Load("Authorization"); ok {
+		return auth.(string)
+	}
+	return ""
+}
+
+// relogin logs in again after a request made with authorization header auth
+// failed because the session expired. If another request already caused a
+// login in the meantime, it returns right away. If a login is in progress, it
+// waits for it to complete.
+func (insta *Instagram) relogin(ctx context.Context, auth string) error {
+	insta.reloginMu.Lock()
+	if insta.reloginPass == "" {
+		insta.reloginMu.Unlock()
+		return errors.New("auto relogin is disabled")
+	}
+	if done := insta.reloginDone; done != nil {
+		// Requests made while logging in have another authorization header,
+		// don't let them wait for themselves
+		if auth == "" || auth != insta.reloginAuth {
+			insta.reloginMu.Unlock()
+			return errors.New("session expired while logging in again")
+		}
+		insta.reloginMu.Unlock()
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		insta.reloginMu.Lock()
+		defer insta.reloginMu.Unlock()
+		return insta.reloginErr
+	}
+	if current := insta.authorization(); current != auth {
+		// Logged in again since the request was sent
+		insta.reloginMu.Unlock()
+		return nil
+	}
+
+	done := make(chan struct{})
+	insta.reloginDone, insta.reloginAuth = done, auth
+	password := insta.reloginPass
+	insta.reloginMu.Unlock()
+
+	insta.log().Warn("Session expired, logging in again")
+
+	// Instagram only replaces the authorization header by a longer one
+	insta.headerOptions.Delete("Authorization")
+	err := insta.Login(password)
+	if errors.Is(err, Err2FARequired) && insta.authorization() != "" {
+		// Logged in with a TOTP code
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to login again: %w", err)
+	} else {
+		insta.sessionChanged()
+	}
+
+	insta.reloginMu.Lock()
+	insta.reloginDone, insta.reloginAuth, insta.reloginErr = nil, "", err
+	insta.reloginMu.Unlock()
+	close(done)
+	return err
+}
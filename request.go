@@ -18,6 +18,10 @@ import (
 )
 
 type reqOptions struct {
+	// Context is used to cancel the request, or set a deadline. If not set,
+	// the context of the Instagram instance will be used, see SetContext.
+	Context context.Context
+
 	// Connection is connection header. Default is "close".
 	Connection string
 
@@ -27,6 +31,9 @@ type reqOptions struct {
 	// Omit API omit the /api/v1/ part of the url
 	OmitAPI bool
 
+	// Upload sends the request to the rupload host, see BaseURLs.Upload
+	Upload bool
+
 	// IsPost set to true will send request with POST method.
 	//
 	// By default this option is false.
@@ -65,6 +72,17 @@ type reqOptions struct {
 	// If Status 429 should be ignored, ErrTooManyRequests. This behaviour should be implemented in
 	//  the wrapper. Goinsta does nothing directly with this value.
 	Ignore429 bool
+
+	// Action performed by the request. The request is not sent while the
+	// action is blocked, see Instagram.Cooldown
+	Action Action
+
+	// Authorization header the request was last sent with
+	Authorization string
+
+	// Set once the request has been sent again after logging in again, see
+	// Instagram.SetAutoRelogin
+	Relogged bool
 }
 
 func (insta *Instagram) sendSimpleRequest(uri string, a ...interface{}) (body []byte, err error) {
@@ -81,15 +99,32 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		return nil, nil, fmt.Errorf("Error while calling %s: %s", o.Endpoint, ErrInstaNotDefined)
 	}
 
-	// Check if a challenge is in progress, if so wait for it to complete (with timeout)
-	if insta.privacyRequested.Get() && !insta.privacyCalled.Get() {
-		if !insta.checkPrivacy() {
+	if o.Context == nil {
+		o.Context = insta.Context()
+	}
+	if err := o.Context.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := insta.checkCooldown(o.Action); err != nil {
+		return nil, nil, err
+	}
+
+	// Check if a challenge is in progress, if so wait for it to complete (with timeout).
+	// Requests accepting the consent are part of it, so don't wait for those.
+	if insta.privacyRequested.Get() && !insta.privacyCalled.Get() && o.Endpoint != urlConsent {
+		if !insta.checkPrivacy(o.Context) {
 			return nil, nil, errors.New("Privacy check timedout")
 		}
 	}
 
 	insta.checkXmidExpiry()
 
+	if insta.rateLimiter != nil {
+		if err := insta.rateLimiter.Wait(o.Context, o.Endpoint); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	method := "GET"
 	if o.IsPost {
 		method = "POST"
@@ -102,21 +137,25 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		o.Timestamp = strconv.FormatInt(time.Now().Unix(), 10)
 	}
 
+	urls := insta.baseURLs
 	var nu string
 	if o.Useb {
-		nu = instaAPIUrlb
+		nu = urls.APIb
 	} else {
-		nu = instaAPIUrl
+		nu = urls.API
 	}
 	if o.UseV2 && !o.Useb {
-		nu = instaAPIUrlv2
+		nu = urls.APIv2
 	} else if o.UseV2 && o.Useb {
-		nu = instaAPIUrlv2b
+		nu = urls.APIv2b
 	}
 	if o.OmitAPI {
-		nu = baseUrl
+		nu = urls.Base
 		o.IgnoreHeaders = append(o.IgnoreHeaders, omitAPIHeadersExclude...)
 	}
+	if o.Upload {
+		nu = urls.Upload
+	}
 
 	nu = nu + o.Endpoint
 	u, err := url.Parse(nu)
@@ -134,7 +173,8 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 
 	// If DataBytes has been passed, use that as data, else use Query
 	if o.DataBytes != nil {
-		reqData = o.DataBytes
+		// Don't consume the original buffer, it's needed if the request is retried
+		reqData = bytes.NewBuffer(o.DataBytes.Bytes())
 	} else {
 		reqData.WriteString(vs.Encode())
 	}
@@ -164,7 +204,7 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 	}
 
 	var req *http.Request
-	req, err = http.NewRequest(method, u.String(), bf)
+	req, err = http.NewRequestWithContext(o.Context, method, u.String(), bf)
 	if err != nil {
 		return
 	}
@@ -234,11 +274,37 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 	setHeaders(headers)
 	setHeaders(o.ExtraHeaders)
 	insta.headerOptions.Range(setHeadersAsync)
+	o.Authorization = req.Header.Get("Authorization")
 
-	resp, err := insta.c.Do(req)
-	if err != nil {
+	r := &Request{Request: req, Endpoint: o.Endpoint, Attempt: o.WrapperCount + 1}
+	if err := insta.beforeSend(r); err != nil {
+		insta.afterReceive(&Response{Request: r, Error: err})
 		return nil, nil, err
 	}
+
+	start := time.Now()
+	resp, err := insta.c.Do(r.Request)
+	if err != nil {
+		insta.log().Debug("Request failed",
+			"endpoint", o.Endpoint,
+			"method", method,
+			"attempt", r.Attempt,
+			"latency", time.Since(start),
+			"error", err,
+		)
+		res := &Response{Request: r, Latency: time.Since(start), Error: err}
+		insta.afterReceive(res)
+
+		// Let the error handlers decide whether transport errors should be retried
+		o.WrapperCount += 1
+		return insta.handleError(
+			&ReqWrapperArgs{
+				insta:      insta,
+				reqOptions: o,
+				Body:       res.Body,
+				Error:      res.Error,
+			})
+	}
 	defer resp.Body.Close()
 
 	insta.extractHeaders(resp.Header)
@@ -247,9 +313,6 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		return nil, nil, err
 	}
 
-	// Extract error from request body, if present
-	err = insta.isError(resp.StatusCode, body, resp.Status, o.Endpoint)
-
 	// Decode gzip encoded responses
 	encoding := resp.Header.Get("Content-Encoding")
 	if encoding != "" && encoding == "gzip" {
@@ -269,36 +332,44 @@ func (insta *Instagram) sendRequest(o *reqOptions) (body []byte, h http.Header,
 		}
 	}
 
-	// Log complete response body
+	// Extract error from request body, if present
+	err = insta.isError(resp.StatusCode, body, resp.Status, o.Endpoint, resp.Header)
+	insta.recordCooldown(o.Action, err)
+
+	// Log request, with the complete response body if debugging
+	latency := time.Since(start)
+	attrs := []any{
+		"endpoint", o.Endpoint,
+		"method", method,
+		"status", resp.StatusCode,
+		"attempt", r.Attempt,
+		"latency", latency,
+	}
 	if insta.Debug {
-		r := map[string]interface{}{
-			"status":   resp.StatusCode,
-			"endpoint": o.Endpoint,
-			"body":     string(body),
-		}
-
-		b, err := json.MarshalIndent(r, "", "  ")
-		if err != nil {
-			return nil, nil, err
-		}
-		insta.debugHandler(string(b))
+		attrs = append(attrs, "body", string(body))
 	}
-
-	// Call Request Wrapper
-	hCopy := resp.Header.Clone()
-	if insta.reqWrapper != nil {
-		o.WrapperCount += 1
-		body, hCopy, err = insta.reqWrapper.GoInstaWrapper(
-			&ReqWrapperArgs{
-				insta:      insta,
-				reqOptions: o,
-				Body:       body,
-				Headers:    hCopy,
-				Error:      err,
-			})
+	insta.log().Debug("Request", attrs...)
+
+	res := &Response{
+		Request:    r,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		Latency:    latency,
+		Error:      err,
 	}
-
-	return body, hCopy, err
+	insta.afterReceive(res)
+
+	// Call error handlers and request wrapper
+	o.WrapperCount += 1
+	return insta.handleError(
+		&ReqWrapperArgs{
+			insta:      insta,
+			reqOptions: o,
+			Body:       res.Body,
+			Headers:    res.Header,
+			Error:      res.Error,
+		})
 }
 
 func (insta *Instagram) checkXmidExpiry() {
@@ -312,12 +383,13 @@ func (insta *Instagram) checkXmidExpiry() {
 		insta.xmidExpiry = -1
 		insta.xmidMu.Unlock()
 		if err := insta.zrToken(); err != nil {
-			insta.warnHandler(errors.Wrap(err, "failed to refresh xmid cookie"))
+			insta.log().Warn("Failed to refresh xmid cookie", "error", err)
 		}
 	}
 }
 
 func (insta *Instagram) extractHeaders(h http.Header) {
+	changed, authChanged := false, false
 	extract := func(in string, out string) {
 		x := h[in]
 		if len(x) > 0 && x[0] != "" {
@@ -333,7 +405,11 @@ func (insta *Instagram) extractHeaders(h http.Header) {
 
 				}
 			}
-			insta.headerOptions.Store(out, x[0])
+			if old, ok := insta.headerOptions.Load(out); !ok || old.(string) != x[0] {
+				insta.headerOptions.Store(out, x[0])
+				changed = true
+				authChanged = authChanged || out == "Authorization"
+			}
 		}
 	}
 
@@ -345,11 +421,19 @@ func (insta *Instagram) extractHeaders(h http.Header) {
 	extract("Ig-Set-Ig-U-Shbts", "Ig-U-Shbts")
 	extract("Ig-Set-Ig-U-Rur", "Ig-U-Rur")
 	extract("Ig-Set-Ig-U-Ds-User-Id", "Ig-U-Ds-User-Id")
+
+	// Only save a new authorization right away, the other headers change on
+	// most responses.
+	if authChanged {
+		insta.sessionChanged()
+	} else if changed {
+		insta.sessionDirty()
+	}
 }
 
-func (insta *Instagram) checkPrivacy() bool {
+func (insta *Instagram) checkPrivacy(parent context.Context) bool {
 	d := time.Now().Add(5 * time.Minute)
-	ctx, cancel := context.WithDeadline(context.Background(), d)
+	ctx, cancel := context.WithDeadline(parent, d)
 	defer cancel()
 
 	for {
@@ -364,7 +448,7 @@ func (insta *Instagram) checkPrivacy() bool {
 	}
 }
 
-func (insta *Instagram) isError(code int, body []byte, status, endpoint string) (err error) {
+func (insta *Instagram) isError(code int, body []byte, status, endpoint string, h http.Header) (err error) {
 	switch code {
 	case 200:
 	case 202:
@@ -374,32 +458,48 @@ func (insta *Instagram) isError(code int, body []byte, status, endpoint string)
 		// Ignore error, doesn't matter if types don't always match up
 		json.Unmarshal(body, &ierr)
 
+		// Instagram often signals rate limits with a 400 instead of a 429
+		if ierr.ErrorType == "rate_limit_error" ||
+			strings.HasPrefix(ierr.Message, "Please wait a few minutes before you try again") {
+			rerr := newAPIError(ierr, ErrTooManyRequests)
+			rerr.Retryable = true
+			rerr.RetryAfter, _ = retryAfter(h)
+			return RateLimitError{rerr}
+		}
+
 		switch ierr.GetMessage() {
 		case "login_required":
 			if ierr.ErrorTitle == "You've Been Logged Out" {
-				return ErrLoggedOut
+				return AuthError{newAPIError(ierr, ErrLoggedOut)}
 			}
-			return ErrLoginRequired
+			return AuthError{newAPIError(ierr, ErrLoginRequired)}
 		case "bad_password":
-			return ErrBadPassword
+			return AuthError{newAPIError(ierr, ErrBadPassword)}
 
 		case "Sorry, this media has been deleted":
-			return ErrMediaDeleted
+			return NotFoundError{newAPIError(ierr, ErrMediaDeleted)}
+
+		case "feedback_required":
+			ferr := FeedbackRequiredError{APIError: newAPIError(ierr, ErrFeedbackRequired)}
+			json.Unmarshal(body, &ferr)
+			return ferr
 
 		case "checkpoint_required":
 			// Usually a request to accept cookies
-			insta.warnHandler(ierr)
+			insta.log().Warn("Checkpoint required", "endpoint", endpoint, "error", ierr)
 			insta.Checkpoint = &ierr.Checkpoint
 			insta.Checkpoint.insta = insta
-			return ErrCheckpointRequired
+			return newChallengeError(ierr, ErrCheckpointRequired)
 
 		case "checkpoint_challenge_required":
 			fallthrough
 		case "challenge_required":
-			insta.warnHandler(ierr)
-			insta.Challenge = ierr.Challenge
-			insta.Challenge.insta = insta
-			return ErrChallengeRequired
+			insta.log().Warn("Challenge required", "endpoint", endpoint, "error", ierr)
+			if ierr.Challenge != nil {
+				insta.Challenge = ierr.Challenge
+				insta.Challenge.insta = insta
+			}
+			return newChallengeError(ierr, ErrChallengeRequired)
 
 		case "two_factor_required":
 			insta.TwoFactorInfo = ierr.TwoFactorInfo
@@ -410,12 +510,13 @@ func (insta *Instagram) isError(code int, body []byte, status, endpoint string)
 					Username: insta.TwoFactorInfo.Username,
 				}
 			}
-			return Err2FARequired
-		case "Please check the code we sent you and try again.":
-			return ErrInvalidCode
+			return AuthError{newAPIError(ierr, Err2FARequired)}
+		case "Please check the code we sent you and try again.",
+			"sms_code_validation_code_invalid":
+			return AuthError{newAPIError(ierr, ErrInvalidCode)}
 
 		default:
-			return ierr
+			return ValidationError{newAPIError(ierr, nil)}
 		}
 
 	case 403:
@@ -423,48 +524,97 @@ func (insta *Instagram) isError(code int, body []byte, status, endpoint string)
 			Code:     403,
 			Endpoint: endpoint,
 		}
-		err = json.Unmarshal(body, &ierr)
-		if err == nil {
-			switch ierr.Message {
-			case "login_required":
-				if ierr.ErrorTitle == "You've Been Logged Out" {
-					return ErrLoggedOut
-				}
-				return ErrLoginRequired
+		if err := json.Unmarshal(body, &ierr); err != nil {
+			// Not a JSON response, e.g. an HTML page
+			ierr.Message = newErrorN(code, body, status, endpoint).Message
+		}
+		switch ierr.Message {
+		case "login_required":
+			if ierr.ErrorTitle == "You've Been Logged Out" {
+				return AuthError{newAPIError(ierr, ErrLoggedOut)}
 			}
-			return ierr
+			return AuthError{newAPIError(ierr, ErrLoginRequired)}
+		case "feedback_required":
+			ferr := FeedbackRequiredError{APIError: newAPIError(ierr, ErrFeedbackRequired)}
+			json.Unmarshal(body, &ferr)
+			return ferr
 		}
-		return err
+		return AuthError{newAPIError(ierr, nil)}
+	case 404:
+		ierr := newErrorN(code, body, status, endpoint)
+		return NotFoundError{APIError{
+			Endpoint:   endpoint,
+			StatusCode: code,
+			ErrorType:  ierr.ErrorType,
+			Message:    ierr.Message,
+			Err:        ErrNotFound,
+			cause:      ierr,
+		}}
 	case 429:
-		return ErrTooManyRequests
-	case 500:
-		ierr := ErrorN{
-			Endpoint:  endpoint,
-			Status:    "500",
-			Message:   string(body),
-			ErrorType: status,
-		}
-		return ierr
+		ierr := newErrorN(code, body, status, endpoint)
+		retryAfter, _ := retryAfter(h)
+		return RateLimitError{APIError{
+			Endpoint:   endpoint,
+			StatusCode: code,
+			ErrorType:  ierr.ErrorType,
+			Message:    ierr.Message,
+			Retryable:  true,
+			RetryAfter: retryAfter,
+			Err:        ErrTooManyRequests,
+			cause:      ierr,
+		}}
 	case 503:
-		return Error503{
-			Message: "Instagram API error. Try it later.",
+		return APIError{
+			Endpoint:   endpoint,
+			StatusCode: code,
+			Message:    "Instagram API error. Try it later.",
+			Retryable:  true,
+			cause: Error503{
+				Message: "Instagram API error. Try it later.",
+			},
 		}
 	default:
-		ierr := ErrorN{
-			Endpoint:  endpoint,
-			Status:    strconv.Itoa(code),
-			Message:   string(body),
-			ErrorType: status,
-		}
-		json.Unmarshal(body, &ierr)
+		ierr := newErrorN(code, body, status, endpoint)
 		if ierr.Message == "Transcode not finished yet." {
 			return nil
 		}
+		if code >= 500 {
+			return APIError{
+				Endpoint:   endpoint,
+				StatusCode: code,
+				ErrorType:  ierr.ErrorType,
+				Message:    ierr.Message,
+				Retryable:  true,
+				cause:      ierr,
+			}
+		}
 		return ierr
 	}
 	return nil
 }
 
+// newErrorN decodes the error returned in a response body. If the body can't
+// be decoded, the start of the body is used as message, rather than the
+// complete body.
+func newErrorN(code int, body []byte, status, endpoint string) ErrorN {
+	ierr := ErrorN{
+		Code:      code,
+		Endpoint:  endpoint,
+		Status:    strconv.Itoa(code),
+		ErrorType: status,
+	}
+	if err := json.Unmarshal(body, &ierr); err != nil || ierr.Message == "" {
+		ierr.Message = string(body)
+		if len(ierr.Message) > 200 {
+			ierr.Message = ierr.Message[:200] + "..."
+		}
+	}
+	// The status field in the body is e.g. "fail", not the status code
+	ierr.Status = strconv.Itoa(code)
+	ierr.Endpoint = endpoint
+	return ierr
+}
+
 func random(min, max int64) int64 {
 	rand.Seed(time.Now().UnixNano())
 	return rand.Int63n(max-min) + min
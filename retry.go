@@ -0,0 +1,176 @@
+package goinsta
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures how the default wrapper retries failed requests.
+// Use NewWrapper to create a wrapper with a custom policy.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a request will be sent,
+	// including the first attempt.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, it doubles with every
+	// consecutive attempt, up until MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// RateLimitDelay is used as base delay instead of BaseDelay if the request
+	// failed with ErrTooManyRequests.
+	RateLimitDelay time.Duration
+
+	// Jitter is the fraction, between 0 and 1, the delay will be randomly
+	// increased or decreased with, to prevent retries from aligning.
+	Jitter float64
+
+	// Retryable reports whether a request that failed with err should be
+	// retried. Defaults to IsRetryable. Requests that aren't idempotent, i.e.
+	// POST requests, are only ever retried if they have been rate limited, or
+	// never reached Instagram, as they could otherwise be performed twice.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns the retry policy used by DefaultWrapper.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    4,
+		BaseDelay:      2 * time.Second,
+		MaxDelay:       5 * time.Minute,
+		RateLimitDelay: TooManyRequestsTimeout,
+		Jitter:         0.2,
+		Retryable:      IsRetryable,
+	}
+}
+
+// IsRetryable reports whether err is a transient error, and the request that
+// caused it can be retried. This is the case for ErrTooManyRequests, status
+// codes of 500 and up, and transport errors such as connection resets. Typed
+// errors, such as RateLimitError, report it themselves, see APIError.
+//
+// Requests that aren't idempotent are retried in fewer cases, see
+// RetryPolicy.Retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrTooManyRequests) {
+		return true
+	}
+
+	var hint retryHinter
+	if errors.As(err, &hint) {
+		retry, _ := hint.RetryHint()
+		return retry
+	}
+
+	var e503 Error503
+	if errors.As(err, &e503) {
+		return true
+	}
+	var errN ErrorN
+	if errors.As(err, &errN) {
+		code := errN.Code
+		if code == 0 {
+			code, _ = strconv.Atoi(errN.Status)
+		}
+		return code >= 500
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func (p *RetryPolicy) retryable(err error, idempotent bool) bool {
+	retry := IsRetryable
+	if p.Retryable != nil {
+		retry = p.Retryable
+	}
+	if !retry(err) {
+		return false
+	}
+	return idempotent || errors.Is(err, ErrTooManyRequests) || notSent(err)
+}
+
+// notSent reports whether err occurred before the request could be sent, e.g.
+// because the connection couldn't be established.
+func notSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// Delay returns the time to wait before retrying a request. Attempt is the
+// number of the attempt that failed, starting at 1. If the response headers
+// contain a Retry-After header, or the error a retry hint, it will be honored,
+// up to MaxDelay.
+func (p *RetryPolicy) Delay(attempt int, err error, h http.Header) time.Duration {
+	if d, ok := retryAfter(h); ok {
+		return p.capDelay(d)
+	}
+	var hint retryHinter
+	if errors.As(err, &hint) {
+		if _, d := hint.RetryHint(); d > 0 {
+			return p.capDelay(d)
+		}
+	}
+
+	d := p.BaseDelay
+	if errors.Is(err, ErrTooManyRequests) && p.RateLimitDelay > d {
+		d = p.RateLimitDelay
+	}
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		d += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(d))
+	}
+	return d
+}
+
+func (p *RetryPolicy) capDelay(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// retryAfter parses the Retry-After header, which can either be a number of
+// seconds or a HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := strings.TrimSpace(h.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
@@ -220,7 +220,7 @@ func (sb *Search) History() (*[]SearchHistory, error) {
 		if errIsFatal(err) {
 			return nil, err
 		}
-		sb.insta.warnHandler("Non fatal error while setting search null state", err)
+		sb.insta.log().Warn("Non fatal error while setting search null state", "error", err)
 	}
 	return h, nil
 }
@@ -350,7 +350,7 @@ func (sb *Search) search(query string, fn func(string) (*SearchResult, error)) (
 		if errIsFatal(err) {
 			return nil, err
 		}
-		insta.warnHandler("Non fatal error while setting search null state", err)
+		insta.log().Warn("Non fatal error while setting search null state", "error", err)
 	}
 
 	var q string
@@ -0,0 +1,249 @@
+package goinsta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSessionSaveDelay is how long saving the session is delayed after
+// Instagram refreshed one of the headers it sends on most responses.
+const DefaultSessionSaveDelay = 30 * time.Second
+
+// ErrSessionNotFound is returned by a SessionStore if no session has been
+// saved for a username.
+var ErrSessionNotFound = errors.New("no session found for this username")
+
+// SessionStore persists sessions, by username. FileStore stores them as files
+// in a directory, see the boltstore package for a bbolt based store.
+//
+// Set a store with Instagram.SetSessionStore, to save the session every time
+// it changes.
+type SessionStore interface {
+	// Load returns the session saved for username, or ErrSessionNotFound
+	Load(username string) (*ConfigFile, error)
+	// Save saves a session, replacing any session saved for the same username
+	Save(config ConfigFile) error
+	// List returns the usernames of all sessions saved
+	List() ([]string, error)
+	// Delete deletes the session saved for username, if any
+	Delete(username string) error
+}
+
+// SetSessionStore sets the store to save the session to. The session is
+// saved right away if logged in, and again every time the login or the
+// authorization header changes. Other headers Instagram refreshes, like
+// Ig-U-Rur and X-Mid, change on almost every response, so they are saved in
+// the background, at most once per delay set with SetSessionSaveDelay. Call
+// SaveSession before exiting to save them right away.
+func (insta *Instagram) SetSessionStore(store SessionStore) error {
+	insta.sessionMu.Lock()
+	insta.sessionStore = store
+	insta.sessionMu.Unlock()
+
+	if store == nil || insta.Account == nil {
+		return nil
+	}
+	return insta.SaveSession()
+}
+
+// SetSessionSaveDelay sets how long saving the session is delayed after
+// Instagram refreshed headers other than the authorization header, to save
+// many changes at once. Defaults to DefaultSessionSaveDelay.
+func (insta *Instagram) SetSessionSaveDelay(d time.Duration) {
+	insta.sessionMu.Lock()
+	defer insta.sessionMu.Unlock()
+	insta.sessionDelay = d
+}
+
+// SaveSession saves the session to the store set with SetSessionStore,
+// including any changes that are waiting to be saved in the background.
+func (insta *Instagram) SaveSession() error {
+	insta.sessionMu.Lock()
+	defer insta.sessionMu.Unlock()
+
+	if insta.sessionTimer != nil {
+		insta.sessionTimer.Stop()
+		insta.sessionTimer = nil
+	}
+
+	if insta.sessionStore == nil {
+		return errors.New("no session store set, please call SetSessionStore first")
+	}
+	if insta.Account == nil {
+		return ErrLoginRequired
+	}
+	return insta.sessionStore.Save(insta.ExportConfig())
+}
+
+// sessionChanged saves the session, if a store has been set, and the user is
+// logged in.
+func (insta *Instagram) sessionChanged() {
+	insta.sessionMu.Lock()
+	store := insta.sessionStore
+	insta.sessionMu.Unlock()
+
+	if store == nil || insta.Account == nil {
+		return
+	}
+	if err := insta.SaveSession(); err != nil {
+		insta.log().Warn("Failed to save session", "error", err)
+	}
+}
+
+// sessionDirty saves the session in the background after the save delay, if
+// a store has been set. Changes made in the meantime are saved along.
+func (insta *Instagram) sessionDirty() {
+	insta.sessionMu.Lock()
+	defer insta.sessionMu.Unlock()
+
+	if insta.sessionStore == nil || insta.sessionTimer != nil {
+		return
+	}
+	d := insta.sessionDelay
+	if d == 0 {
+		d = DefaultSessionSaveDelay
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		insta.sessionMu.Lock()
+		pending := insta.sessionTimer == timer
+		if pending {
+			insta.sessionTimer = nil
+		}
+		insta.sessionMu.Unlock()
+
+		// Saved in the meantime
+		if !pending {
+			return
+		}
+		insta.sessionChanged()
+	})
+	insta.sessionTimer = timer
+}
+
+// LoadSession imports the session saved for username, and sets the store, so
+// changes to the session are saved.
+//
+// Add optional bool:true parameter to prevent account sync on import, see
+// ImportConfig.
+func LoadSession(store SessionStore, username string, args ...interface{}) (*Instagram, error) {
+	config, err := store.Load(username)
+	if err != nil {
+		return nil, err
+	}
+	insta, err := ImportConfig(*config, args...)
+	if err != nil {
+		return nil, err
+	}
+	insta.sessionMu.Lock()
+	insta.sessionStore = store
+	insta.sessionMu.Unlock()
+	return insta, nil
+}
+
+// FileStore is a SessionStore that saves every session as a JSON file in a
+// directory, named after the username.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store that saves sessions in dir, creating it if
+// needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(username string) (string, error) {
+	if username == "" || strings.ContainsAny(username, `/\`) || strings.HasPrefix(username, ".") {
+		return "", fmt.Errorf("invalid username %q", username)
+	}
+	return filepath.Join(s.dir, username+".json"), nil
+}
+
+// Load returns the session saved for username.
+func (s *FileStore) Load(username string) (*ConfigFile, error) {
+	path, err := s.path(username)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	config := &ConfigFile{}
+	if err := json.Unmarshal(b, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Save saves a session. The file is replaced atomically, so a crash while
+// saving never leaves a corrupt session behind.
+func (s *FileStore) Save(config ConfigFile) error {
+	path, err := s.path(config.User)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// List returns the usernames of all sessions saved, sorted.
+func (s *FileStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		users = append(users, strings.TrimSuffix(name, ".json"))
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+// Delete deletes the session saved for username.
+func (s *FileStore) Delete(username string) error {
+	path, err := s.path(username)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package goinsta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ChallengeSolver solves the challenges and checkpoints Instagram responds
+// with, see Challenge.Process and Checkpoint.Process.
+//
+// HTTPSolver solves challenges through the challenge API, like the app does.
+// CallbackSolver does the same, and asks your application for the security
+// code Instagram sends by SMS or email. If goinsta is built with the headless
+// build tag, HeadlessSolver opens challenges in a headless Chrome browser.
+type ChallengeSolver interface {
+	// SelectMethod selects how to verify the account, e.g. by SMS or email,
+	// and reports whether a security code has been sent, that needs to be
+	// received and submitted.
+	SelectMethod(ctx context.Context, c *Challenge) (codeSent bool, err error)
+
+	// ReceiveCode returns the security code Instagram has sent.
+	ReceiveCode(ctx context.Context, c *Challenge) (string, error)
+
+	// SubmitCode submits the security code, solving the challenge.
+	SubmitCode(ctx context.Context, c *Challenge, code string) error
+
+	// AcceptCookies accepts the cookie consent a checkpoint prompts for.
+	AcceptCookies(ctx context.Context, c *Checkpoint) error
+}
+
+// SetChallengeSolver sets the solver used to solve challenges and
+// checkpoints. By default HTTPSolver is used, or HeadlessSolver if goinsta is
+// built with the headless build tag.
+func (insta *Instagram) SetChallengeSolver(solver ChallengeSolver) {
+	insta.challengeSolver = solver
+}
+
+func (insta *Instagram) solver() ChallengeSolver {
+	if insta.challengeSolver == nil {
+		return defaultChallengeSolver()
+	}
+	return insta.challengeSolver
+}
+
+// HTTPSolver solves challenges through the challenge API, the way the app
+// does, without a browser. Challenges that only need confirming it was you
+// are solved right away. If Instagram sends a security code, it is requested
+// from the code provider set with Instagram.SetCodeProvider.
+type HTTPSolver struct {
+	// Choice is the verification method to select, "0" for SMS, or "1" for
+	// email. Defaults to the method Instagram suggests.
+	Choice string
+}
+
+// SelectMethod fetches the current step of the challenge, and selects the
+// verification method.
+func (s *HTTPSolver) SelectMethod(ctx context.Context, c *Challenge) (bool, error) {
+	if err := c.updateState(ctx); err != nil {
+		return false, err
+	}
+
+	switch c.Step() {
+	case "select_verify_method":
+		choice := s.Choice
+		if choice == "" {
+			choice = c.suggestedChoice()
+		}
+		if err := c.selectVerifyMethod(ctx, choice); err != nil {
+			return false, err
+		}
+	case "delta_login_review":
+		if err := c.deltaLoginReview(ctx); err != nil {
+			return false, err
+		}
+	case "verify_code", "verify_email", "verify_sms":
+		// Code has already been sent
+	default:
+		return false, ErrChallengeProcess{StepName: c.Step()}
+	}
+	return c.CodeRequired(), nil
+}
+
+// ReceiveCode asks the code provider for the security code, see
+// Instagram.SetCodeProvider. If none has been set, ErrChallengeNoCode is
+// returned.
+func (s *HTTPSolver) ReceiveCode(ctx context.Context, c *Challenge) (string, error) {
+	method := CodeEmail
+	if c.Step() == "verify_sms" || c.StepData.FormType == "phone_number" {
+		method = CodeSMS
+	}
+	return c.insta.requestCode(ctx, CodeRequest{
+		Username:    c.insta.user,
+		Reason:      CodeForChallenge,
+		Method:      method,
+		Destination: c.StepData.ContactPoint,
+	}, ErrChallengeNoCode)
+}
+
+// SubmitCode submits the security code to the challenge API.
+func (s *HTTPSolver) SubmitCode(ctx context.Context, c *Challenge, code string) error {
+	return c.SendSecurityCodeContext(ctx, code)
+}
+
+// AcceptCookies accepts the cookie consent through the consent API.
+func (s *HTTPSolver) AcceptCookies(ctx context.Context, c *Checkpoint) error {
+	return c.insta.acceptConsent(ctx)
+}
+
+// CallbackSolver solves challenges like HTTPSolver, calling Code to get the
+// security code Instagram has sent, e.g. by asking the user for it.
+type CallbackSolver struct {
+	HTTPSolver
+
+	// Code returns the security code sent by SMS or email. The contact point
+	// the code has been sent to is in c.StepData.ContactPoint.
+	Code func(ctx context.Context, c *Challenge) (string, error)
+}
+
+// NewCallbackSolver creates a solver that calls code to get the security code
+// Instagram has sent.
+func NewCallbackSolver(code func(ctx context.Context, c *Challenge) (string, error)) *CallbackSolver {
+	return &CallbackSolver{Code: code}
+}
+
+// ReceiveCode calls Code to get the security code.
+func (s *CallbackSolver) ReceiveCode(ctx context.Context, c *Challenge) (string, error) {
+	if s.Code == nil {
+		return "", ErrChallengeNoCode
+	}
+	return s.Code(ctx, c)
+}
+
+// acceptConsent accepts the cookie consent, by walking through the screens of
+// the consent flow.
+func (insta *Instagram) acceptConsent(ctx context.Context) error {
+	screens := []struct {
+		key     string
+		updates string
+	}{
+		{"qp_intro", `{"existing_user_intro_state":"2"}`},
+		{"tos_and_two_age_button", `{"age_consent_state":"2","tos_data_policy_consent_state":"2"}`},
+	}
+
+	var uid int64
+	if insta.Account != nil {
+		uid = insta.Account.ID
+	}
+	for _, screen := range screens {
+		data, err := json.Marshal(map[string]string{
+			"current_screen_key": screen.key,
+			"updates":            screen.updates,
+			"_uid":               toString(uid),
+			"_uuid":              insta.uuid,
+			"device_id":          insta.dID,
+		})
+		if err != nil {
+			return err
+		}
+
+		body, _, err := insta.sendRequest(&reqOptions{
+			Context:  ctx,
+			Endpoint: urlConsent,
+			IsPost:   true,
+			Query:    generateSignature(data),
+		})
+		if err != nil {
+			return err
+		}
+
+		var resp struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return err
+		}
+		if resp.Status != "ok" {
+			return errors.New("failed to accept cookie consent, status: " + resp.Status)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func TestSetBaseURLs(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+	if insta.BaseURLs() != goinsta.DefaultBaseURLs() {
+		t.Fatalf("Expected default base URLs, got %+v", insta.BaseURLs())
+	}
+
+	if err := insta.SetBaseURLs(goinsta.BaseURLs{API: "/api/v1/"}); err == nil {
+		t.Fatal("Expected relative URL to be rejected")
+	}
+
+	err := insta.SetBaseURLs(goinsta.BaseURLs{API: "https://gateway.example.com/api/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := insta.BaseURLs()
+	if urls.API != "https://gateway.example.com/api/v1/" {
+		t.Fatalf("Expected trailing slash to be added, got %s", urls.API)
+	}
+	if urls.Upload != goinsta.DefaultBaseURLs().Upload {
+		t.Fatalf("Expected empty URLs to default, got %s", urls.Upload)
+	}
+
+	// Base URLs are persisted in the config
+	insta.Account = &goinsta.Account{ID: 1, Username: "goinsta_test"}
+	config := insta.ExportConfig()
+	imported, err := goinsta.ImportConfig(config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.BaseURLs() != urls {
+		t.Fatalf("Base URLs were not imported, got %+v", imported.BaseURLs())
+	}
+
+	// Configs without base URLs use the defaults
+	config.BaseURLs = nil
+	imported, err = goinsta.ImportConfig(config, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.BaseURLs() != goinsta.DefaultBaseURLs() {
+		t.Fatalf("Expected default base URLs, got %+v", imported.BaseURLs())
+	}
+}
+
+func TestBaseURLsUploadHost(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+
+	// Upload host that forwards to the fake server, and keeps track of paths
+	target, _ := url.Parse(srv.URL)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	var mu sync.Mutex
+	var paths []string
+	uploads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		proxy.ServeHTTP(w, r)
+	}))
+	defer uploads.Close()
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	urls := insta.BaseURLs()
+	urls.Upload = uploads.URL
+	if err := insta.SetBaseURLs(urls); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := insta.Upload(&goinsta.UploadOptions{File: testJPEG(t, 320, 240)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || !strings.HasPrefix(paths[0], "/rupload_igphoto/") {
+		t.Fatalf("Expected only the photo upload on the upload host, got %v", paths)
+	}
+}
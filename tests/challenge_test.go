@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func TestChallengeCallbackSolver(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.RequireChallenge(alice.ID, "123456")
+
+	var sentTo []string
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	insta.SetChallengeSolver(goinsta.NewCallbackSolver(
+		func(ctx context.Context, c *goinsta.Challenge) (string, error) {
+			sentTo = append(sentTo, c.StepData.ContactPoint)
+			return "123456", nil
+		},
+	))
+
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sentTo) != 1 || sentTo[0] != "a***@example.com" {
+		t.Fatalf("Expected to be asked for the code sent by email once, got %v", sentTo)
+	}
+	if srv.HasChallenge(alice.ID) {
+		t.Fatal("Expected challenge to be solved")
+	}
+	if insta.Account == nil || insta.Account.ID != alice.ID {
+		t.Fatal("Expected to be logged in after solving the challenge")
+	}
+}
+
+func TestChallengeHTTPSolver(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	// Confirming it was you doesn't need a code
+	srv.RequireChallenge(alice.ID, "")
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A code can't be received without a callback
+	srv.RequireChallenge(alice.ID, "123456")
+	insta = goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); !errors.Is(err, goinsta.ErrChallengeNoCode) {
+		t.Fatalf("Expected ErrChallengeNoCode, got: %v", err)
+	}
+}
+
+func TestCheckpointConsent(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	insta.SetInfoHandler(t.Log)
+	srv.RequireConsent(alice.ID)
+
+	if err := insta.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if insta.Account.Username != "alice" {
+		t.Fatalf("Expected account to be synced, got %s", insta.Account.Username)
+	}
+}
+
+func TestChallengeCancel(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.RequireChallenge(alice.ID, "123456")
+
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); !errors.Is(err, goinsta.ErrChallengeNoCode) {
+		t.Fatalf("Expected ErrChallengeNoCode, got: %v", err)
+	}
+
+	// Cancelled while the user enters the code, the context of the instance
+	// itself is still valid.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	insta.SetChallengeSolver(goinsta.NewCallbackSolver(
+		func(ctx context.Context, c *goinsta.Challenge) (string, error) {
+			cancel()
+			return "123456", nil
+		},
+	))
+
+	if err := insta.Challenge.ProcessContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if !srv.HasChallenge(alice.ID) {
+		t.Fatal("Expected the code not to be submitted")
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func TestCodeProviderTwoFactor(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.EnableTwoFactor(alice.ID, "424242")
+
+	var reqs []goinsta.CodeRequest
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	insta.SetCodeProvider(func(ctx context.Context, req goinsta.CodeRequest) (string, error) {
+		reqs = append(reqs, req)
+		return "424242", nil
+	})
+
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("Expected to be asked for a code once, got %d requests", len(reqs))
+	}
+	req := reqs[0]
+	if req.Username != "alice" || req.Reason != goinsta.CodeForTwoFactor ||
+		req.Method != goinsta.CodeSMS || req.Destination != "00" {
+		t.Fatalf("Unexpected code request: %+v", req)
+	}
+	if insta.Account == nil || insta.Account.ID != alice.ID {
+		t.Fatal("Expected to be logged in after submitting the code")
+	}
+}
+
+func TestCodeProviderTwoFactorGiveUp(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.EnableTwoFactor(alice.ID, "424242")
+
+	// Without a provider, the caller has to call Login2FA
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); !errors.Is(err, goinsta.Err2FARequired) {
+		t.Fatalf("Expected Err2FARequired, got: %v", err)
+	}
+	if err := insta.TwoFactorInfo.Login2FA("424242"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Errors of the provider are returned as is
+	errGaveUp := errors.New("operator gave up")
+	insta = goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	insta.SetCodeProvider(func(ctx context.Context, req goinsta.CodeRequest) (string, error) {
+		return "", errGaveUp
+	})
+	if err := insta.Login(); !errors.Is(err, errGaveUp) {
+		t.Fatalf("Expected the error of the provider, got: %v", err)
+	}
+}
+
+func TestCodeProviderLogin2FA(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.EnableTwoFactor(alice.ID, "424242")
+
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); !errors.Is(err, goinsta.Err2FARequired) {
+		t.Fatalf("Expected Err2FARequired, got: %v", err)
+	}
+	if err := insta.TwoFactorInfo.Login2FA(); !errors.Is(err, goinsta.Err2FANoCode) {
+		t.Fatalf("Expected Err2FANoCode, got: %v", err)
+	}
+
+	// Without a code, Login2FA asks the provider set afterwards
+	var asked int
+	insta.SetCodeProvider(func(ctx context.Context, req goinsta.CodeRequest) (string, error) {
+		asked++
+		return "424242", nil
+	})
+	if err := insta.TwoFactorInfo.Login2FA(); err != nil {
+		t.Fatal(err)
+	}
+	if asked != 1 {
+		t.Fatalf("Expected to be asked for a code once, got %d requests", asked)
+	}
+	if insta.Account == nil || insta.Account.ID != alice.ID {
+		t.Fatal("Expected to be logged in after submitting the code")
+	}
+}
+
+func TestCodeProviderChallenge(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.RequireChallenge(alice.ID, "123456")
+
+	var reqs []goinsta.CodeRequest
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	insta.SetChallengeSolver(&goinsta.HTTPSolver{Choice: "0"})
+	insta.SetCodeProvider(func(ctx context.Context, req goinsta.CodeRequest) (string, error) {
+		reqs = append(reqs, req)
+		return "123456", nil
+	})
+
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	if len(reqs) != 1 {
+		t.Fatalf("Expected to be asked for a code once, got %d requests", len(reqs))
+	}
+	req := reqs[0]
+	if req.Reason != goinsta.CodeForChallenge || req.Method != goinsta.CodeSMS ||
+		req.Destination != "+1 *** ***-**-00" {
+		t.Fatalf("Unexpected code request: %+v", req)
+	}
+	if srv.HasChallenge(alice.ID) {
+		t.Fatal("Expected challenge to be solved")
+	}
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+func TestConfigMigration(t *testing.T) {
+	// A config exported before configs were versioned, and before the
+	// device, family id and base URLs were exported.
+	old := `{
+		"id": 1,
+		"username": "alice",
+		"device_id": "android-1234567890abcdef",
+		"uuid": "71cd1aec-e146-4380-8d60-d216127c7b4e",
+		"phone_id": "fbf767a4-260a-490d-bcbb-ee7c9ed7c576",
+		"header_options": {"Authorization": "Bearer IGT:2:token"},
+		"account": {"pk": 1, "username": "alice"}
+	}`
+	insta, err := goinsta.ImportFromBytes([]byte(old), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := insta.ExportConfig()
+	if config.Version != goinsta.ConfigVersion {
+		t.Fatalf("Expected config version %d, got %d", goinsta.ConfigVersion, config.Version)
+	}
+	if config.Device != goinsta.GalaxyS10 {
+		t.Fatalf("Expected default device, got %+v", config.Device)
+	}
+	if config.FamilyID == "" {
+		t.Fatal("Expected family id to be generated")
+	}
+	if config.BaseURLs == nil || *config.BaseURLs != goinsta.DefaultBaseURLs() {
+		t.Fatalf("Expected default base URLs, got %+v", config.BaseURLs)
+	}
+	if config.HeaderOptions["Authorization"] != "Bearer IGT:2:token" {
+		t.Fatal("Expected header options to be kept")
+	}
+}
+
+func TestConfigVersionTooNew(t *testing.T) {
+	newer := fmt.Sprintf(`{"version": %d, "id": 1, "username": "alice", "account": {"pk": 1}}`,
+		goinsta.ConfigVersion+1)
+	if _, err := goinsta.ImportFromBytes([]byte(newer), true); !errors.Is(err, goinsta.ErrConfigVersion) {
+		t.Fatalf("Expected ErrConfigVersion, got: %v", err)
+	}
+}
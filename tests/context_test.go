@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// blockingTransport never answers, until the request context is done.
+type blockingTransport struct{}
+
+func (blockingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	<-r.Context().Done()
+	return nil, r.Context().Err()
+}
+
+// tooManyRequestsTransport always answers with status code 429.
+type tooManyRequestsTransport struct{}
+
+func (tooManyRequestsTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Status:     "429 Too Many Requests",
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"status":"fail"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestContextCancelled(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(blockingTransport{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	insta.SetContext(ctx)
+
+	err := insta.Login()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(blockingTransport{})
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if feed.NextContext(ctx) {
+		t.Fatal("Expected pagination to fail")
+	}
+	if !errors.Is(feed.Error(), context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", feed.Error())
+	}
+}
+
+func TestContextTooManyRequests(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(tooManyRequestsTransport{})
+	insta.SetWarnHandler(func(...interface{}) {})
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if feed.NextContext(ctx) {
+		t.Fatal("Expected pagination to fail")
+	}
+	if time.Since(start) > goinsta.TooManyRequestsTimeout/2 {
+		t.Fatal("Wrapper did not stop sleeping after the context was cancelled")
+	}
+	if !errors.Is(feed.Error(), context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", feed.Error())
+	}
+}
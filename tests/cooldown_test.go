@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// countTransport counts the requests passed on to the transport it wraps.
+type countTransport struct {
+	http.RoundTripper
+	calls int32
+}
+
+func (c *countTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.calls, 1)
+	return c.RoundTripper.RoundTrip(r)
+}
+
+func TestActionCooldown(t *testing.T) {
+	transport := &countTransport{RoundTripper: &statusTransport{
+		status: 400,
+		body:   `{"message":"feedback_required","spam":true,"feedback_title":"Try Again Later","status":"fail"}`,
+	}}
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(transport)
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{MaxAttempts: 1}))
+	insta.SetActionCooldown(time.Hour)
+	insta.Account = &goinsta.Account{ID: 1, Username: "goinsta_test"}
+
+	user := insta.NewUser()
+	user.ID = 2
+	err := user.Follow()
+	if !errors.As(err, new(goinsta.FeedbackRequiredError)) {
+		t.Fatalf("Expected FeedbackRequiredError, got %T: %v", err, err)
+	}
+	until := insta.Cooldown(goinsta.ActionFollow)
+	if d := time.Until(until); d < 59*time.Minute || d > time.Hour {
+		t.Fatalf("Expected follow cooldown of an hour, got %s", d)
+	}
+	if !insta.Cooldown(goinsta.ActionLike).IsZero() {
+		t.Fatal("Expected likes not to be blocked")
+	}
+
+	// Following should now fail without sending a request
+	err = user.Unfollow()
+	var cerr goinsta.CooldownError
+	if !errors.As(err, &cerr) || cerr.Action != goinsta.ActionFollow || !cerr.Until.Equal(until) {
+		t.Fatalf("Expected CooldownError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, goinsta.ErrFeedbackRequired) {
+		t.Fatal("Expected CooldownError to wrap ErrFeedbackRequired")
+	}
+	if transport.calls != 1 {
+		t.Fatalf("Expected 1 request, got %d", transport.calls)
+	}
+
+	// The cooldown should survive an export and import
+	buf := new(bytes.Buffer)
+	if err := insta.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportReader(buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported.Cooldown(goinsta.ActionFollow).Equal(until) {
+		t.Fatalf("Expected cooldown until %s after import, got %s", until, imported.Cooldown(goinsta.ActionFollow))
+	}
+
+	// Expired cooldowns are not exported
+	insta.SetCooldown(goinsta.ActionLike, time.Now().Add(-time.Minute))
+	if _, ok := insta.ExportConfig().Cooldowns[goinsta.ActionLike]; ok {
+		t.Fatal("Expected expired cooldown not to be exported")
+	}
+
+	insta.SetCooldown(goinsta.ActionFollow, time.Time{})
+	user.Follow()
+	if transport.calls != 2 {
+		t.Fatalf("Expected request to be sent after lifting the cooldown, got %d requests", transport.calls)
+	}
+}
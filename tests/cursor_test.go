@@ -0,0 +1,143 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+// checkpoint serializes the cursor, like a crawler storing it would.
+func checkpoint(t *testing.T, c goinsta.Cursor) goinsta.Cursor {
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored goinsta.Cursor
+	if err := json.Unmarshal(b, &restored); err != nil {
+		t.Fatal(err)
+	}
+	return restored
+}
+
+func TestCursorFeed(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.SetPageSize(2)
+	addPosts(srv, alice.ID, 5)
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	feed := insta.Account.Feed()
+	if !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+	var captions []string
+	for _, item := range feed.Items {
+		captions = append(captions, item.Caption.Text)
+	}
+	cursor := checkpoint(t, feed.PageCursor())
+
+	// Resume in a new session, as after a restart
+	insta = fakeLogin(t, srv, "alice", "secret")
+	feed, err := insta.ResumeFeedMedia(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for feed.Next() {
+	}
+	if err := feed.Error(); !errors.Is(err, goinsta.ErrNoMore) {
+		t.Fatal(err)
+	}
+	for _, item := range feed.Items {
+		captions = append(captions, item.Caption.Text)
+	}
+	expected := "[post 5 post 4 post 3 post 2 post 1]"
+	if fmt.Sprint(captions) != expected {
+		t.Fatalf("Expected %s, got %v", expected, captions)
+	}
+
+	// A cursor taken at the end resumes at the end
+	feed, err = insta.ResumeFeedMedia(checkpoint(t, feed.PageCursor()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if feed.Next() {
+		t.Fatal("Expected no more pages")
+	}
+}
+
+func TestCursorUsers(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+	srv.SetPageSize(2)
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	for i := 0; i < 5; i++ {
+		srv.AddUser(fmt.Sprintf("user%d", i), "secret")
+		u := fakeLogin(t, srv, fmt.Sprintf("user%d", i), "secret")
+		profile, err := u.Profiles.ByName("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := profile.Follow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Resume after every page
+	seen := map[int64]bool{}
+	users := insta.Account.Followers("")
+	for pages := 0; users.Next(); pages++ {
+		if pages > 5 {
+			t.Fatal("Pagination doesn't end")
+		}
+		for _, u := range users.Users {
+			if seen[u.ID] {
+				t.Fatalf("User %s fetched twice", u.Username)
+			}
+			seen[u.ID] = true
+		}
+
+		var err error
+		users, err = insta.ResumeUsers(checkpoint(t, users.PageCursor()))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := users.Error(); !errors.Is(err, goinsta.ErrNoMore) {
+		t.Fatal(err)
+	}
+	for _, u := range users.Users {
+		seen[u.ID] = true
+	}
+	if len(seen) != 5 {
+		t.Fatalf("Expected 5 followers, got %d", len(seen))
+	}
+}
+
+func TestCursorKind(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	cursor := insta.Account.Feed().PageCursor()
+	if _, err := insta.ResumeUsers(cursor); !errors.Is(err, goinsta.ErrCursorKind) {
+		t.Fatalf("Expected ErrCursorKind, got: %v", err)
+	}
+}
+
+func TestCursorCommentsNoMedia(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+
+	// Comments that don't belong to a post have no cursor to resume from
+	cursor := (&goinsta.Comments{}).PageCursor()
+	if _, err := insta.ResumeComments(cursor); !errors.Is(err, goinsta.ErrCursorKind) {
+		t.Fatalf("Expected ErrCursorKind, got: %v", err)
+	}
+}
@@ -0,0 +1,256 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func addImage(srv *goinstatest.Server, user int64, caption string, takenAt time.Time) *goinstatest.Media {
+	return srv.AddMedia(user, goinstatest.Media{
+		Caption: caption,
+		Width:   100,
+		Height:  100,
+		Data:    []byte("image data of " + caption),
+		TakenAt: takenAt,
+	})
+}
+
+func checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func syncUser(t *testing.T, insta *goinsta.Instagram, dir, username string, full bool) *goinsta.DownloadStats {
+	d, err := insta.NewDownloader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Workers = 2
+	d.Full = full
+	user, err := insta.Profiles.ByName(username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := d.SyncUser(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func TestDownloaderSync(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.SetPageSize(2)
+	now := time.Now().Truncate(time.Second)
+	var posts []*goinstatest.Media
+	for i := 0; i < 5; i++ {
+		posts = append(posts, addImage(srv, alice.ID, fmt.Sprintf("post %d", i), now.Add(time.Duration(i-10)*time.Hour)))
+	}
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	dir := t.TempDir()
+	stats := syncUser(t, insta, dir, "alice", false)
+	if stats.Downloaded != 5 || stats.Failed != 0 {
+		t.Fatalf("Expected 5 downloads, got %+v", stats)
+	}
+
+	d, err := insta.NewDownloader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := d.Manifest()
+	for _, post := range posts {
+		id := fmt.Sprintf("%d_%d", post.ID, alice.ID)
+		entry := manifest.Media[id]
+		if entry == nil {
+			t.Fatalf("Post %s missing from manifest", id)
+		}
+		if entry.Caption != post.Caption || entry.TakenAt != post.TakenAt.Unix() || entry.Source != "feed" {
+			t.Fatalf("Unexpected manifest entry: %+v", entry)
+		}
+		f := entry.Files[0]
+		b, err := os.ReadFile(filepath.Join(dir, f.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Path != "alice/feed/"+id+".jpg" || f.SHA256 != checksum(post.Data) || checksum(b) != f.SHA256 {
+			t.Fatalf("Unexpected file: %+v", f)
+		}
+	}
+
+	// Only the new post is downloaded
+	addImage(srv, alice.ID, "new post", now)
+	stats = syncUser(t, insta, dir, "alice", false)
+	if stats.Downloaded != 1 || stats.Skipped != 0 {
+		t.Fatalf("Expected only the new post to be downloaded, got %+v", stats)
+	}
+
+	// A full sync restores missing files
+	old := filepath.Join(dir, "alice", "feed", fmt.Sprintf("%d_%d.jpg", posts[0].ID, alice.ID))
+	if err := os.Remove(old); err != nil {
+		t.Fatal(err)
+	}
+	stats = syncUser(t, insta, dir, "alice", true)
+	if stats.Downloaded != 1 || stats.Skipped != 5 {
+		t.Fatalf("Expected the missing post to be downloaded, got %+v", stats)
+	}
+	if _, err := os.Stat(old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownloaderResume(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	post := addImage(srv, alice.ID, "post", time.Now().Add(-time.Hour))
+
+	// Leave a partial download behind, with a marker to tell it has been
+	// resumed rather than downloaded again
+	dir := t.TempDir()
+	dst := partialDownload(t, dir, alice, post, fmt.Sprintf("%s/media/%d.jpg", srv.URL, post.ID))
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	stats := syncUser(t, insta, dir, "alice", false)
+	if stats.Downloaded != 1 {
+		t.Fatalf("Expected 1 download, got %+v", stats)
+	}
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "XXXXX" + string(post.Data[5:])
+	if string(b) != expected {
+		t.Fatalf("Expected %q, got %q", expected, b)
+	}
+	for _, name := range []string{dst + ".part", dst + ".part.url"} {
+		if _, err := os.Stat(name); !os.IsNotExist(err) {
+			t.Fatalf("Expected %s to be removed", name)
+		}
+	}
+}
+
+// partialDownload leaves the partial download of post behind, of the URL
+// provided, and returns the path the post is downloaded to.
+func partialDownload(t *testing.T, dir string, alice *goinstatest.User, post *goinstatest.Media, url string) string {
+	dst := filepath.Join(dir, "alice", "feed", fmt.Sprintf("%d_%d.jpg", post.ID, alice.ID))
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst+".part", []byte("XXXXX"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst+".part.url", []byte(url), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dst
+}
+
+// fullRangeTransport answers range requests with the complete file, but
+// status code 206, as some proxies do.
+type fullRangeTransport struct{}
+
+func (fullRangeTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil || r.Header.Get("Range") == "" || resp.StatusCode != http.StatusPartialContent {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	full := r.Clone(r.Context())
+	full.Header.Del("Range")
+	resp, err = http.DefaultTransport.RoundTrip(full)
+	if err != nil {
+		return nil, err
+	}
+	resp.StatusCode = http.StatusPartialContent
+	resp.Header.Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", resp.ContentLength-1, resp.ContentLength))
+	return resp, nil
+}
+
+func TestDownloaderRestart(t *testing.T) {
+	tests := []struct {
+		name      string
+		url       func(srv *goinstatest.Server, post *goinstatest.Media) string
+		transport http.RoundTripper
+	}{
+		{
+			name: "other url",
+			url: func(srv *goinstatest.Server, post *goinstatest.Media) string {
+				return srv.URL + "/media/1.jpg"
+			},
+		},
+		{
+			name: "other range",
+			url: func(srv *goinstatest.Server, post *goinstatest.Media) string {
+				return fmt.Sprintf("%s/media/%d.jpg", srv.URL, post.ID)
+			},
+			transport: fullRangeTransport{},
+		},
+	}
+
+	for _, test := range tests {
+		srv := goinstatest.NewServer()
+		alice := srv.AddUser("alice", "secret")
+		post := addImage(srv, alice.ID, "post", time.Now().Add(-time.Hour))
+		dir := t.TempDir()
+		dst := partialDownload(t, dir, alice, post, test.url(srv, post))
+
+		insta := fakeLogin(t, srv, "alice", "secret")
+		if test.transport != nil {
+			insta.SetHTTPTransport(test.transport)
+		}
+		stats := syncUser(t, insta, dir, "alice", false)
+		srv.Close()
+		if stats.Downloaded != 1 {
+			t.Fatalf("%s: expected 1 download, got %+v", test.name, stats)
+		}
+		b, err := os.ReadFile(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(b, post.Data) {
+			t.Fatalf("%s: expected the download to start over, got %q", test.name, b)
+		}
+	}
+}
+
+func TestDownloaderVerify(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	post := addImage(srv, alice.ID, "post", time.Now().Add(-time.Hour))
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	dir := t.TempDir()
+	syncUser(t, insta, dir, "alice", false)
+
+	// A corrupted file of the same size is downloaded again
+	dst := filepath.Join(dir, "alice", "feed", fmt.Sprintf("%d_%d.jpg", post.ID, alice.ID))
+	if err := os.WriteFile(dst, bytes.Repeat([]byte("X"), len(post.Data)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stats := syncUser(t, insta, dir, "alice", true)
+	if stats.Downloaded != 1 {
+		t.Fatalf("Expected the corrupted file to be downloaded again, got %+v", stats)
+	}
+	b, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, post.Data) {
+		t.Fatalf("Expected %q, got %q", post.Data, b)
+	}
+}
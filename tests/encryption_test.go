@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+func TestEncryptedExport(t *testing.T) {
+	insta := newPoolInsta(t, "alice", okTransport{})
+	insta.SetTOTPSeed("JBSWY3DPEHPK3PXP")
+	config := insta.ExportConfig()
+	config.HeaderOptions["Authorization"] = "Bearer IGT:2:secret-token"
+
+	keys := map[string]goinsta.SessionKey{
+		"passphrase": goinsta.Passphrase("correct horse battery staple"),
+		"raw key":    goinsta.RawKey(bytes.Repeat([]byte{7}, 32)),
+	}
+	for name, key := range keys {
+		t.Run(name, func(t *testing.T) {
+			b, err := goinsta.EncryptConfig(config, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, secret := range []string{"secret-token", "JBSWY3DPEHPK3PXP", insta.ExportConfig().DeviceID} {
+				if bytes.Contains(b, []byte(secret)) {
+					t.Fatalf("Encrypted session contains %q in plaintext", secret)
+				}
+			}
+
+			decrypted, err := goinsta.DecryptConfig(b, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decrypted.HeaderOptions["Authorization"] != "Bearer IGT:2:secret-token" ||
+				decrypted.DeviceID != config.DeviceID {
+				t.Fatalf("Decrypted session doesn't match, got %+v", decrypted)
+			}
+
+			// Plain imports should refuse encrypted sessions
+			if _, err := goinsta.ImportFromBytes(b, true); !errors.Is(err, goinsta.ErrSessionEncrypted) {
+				t.Fatalf("Expected ErrSessionEncrypted, got: %v", err)
+			}
+		})
+	}
+
+	b, err := goinsta.EncryptConfig(config, keys["passphrase"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := goinsta.DecryptConfig(b, goinsta.Passphrase("wrong")); !errors.Is(err, goinsta.ErrWrongSessionKey) {
+		t.Fatalf("Expected ErrWrongSessionKey, got: %v", err)
+	}
+
+	// Tampering with the envelope should be detected
+	tampered := bytes.Replace(b, []byte(`"p":1`), []byte(`"p":2`), 1)
+	if _, err := goinsta.DecryptConfig(tampered, keys["passphrase"]); !errors.Is(err, goinsta.ErrWrongSessionKey) {
+		t.Fatalf("Expected tampered session to fail, got: %v", err)
+	}
+
+	newer := bytes.Replace(b, []byte(`"goinsta_encrypted":1`), []byte(`"goinsta_encrypted":99`), 1)
+	if _, err := goinsta.DecryptConfig(newer, keys["passphrase"]); err == nil {
+		t.Fatal("Expected unknown envelope version to be rejected")
+	}
+}
+
+func TestEncryptedMalformed(t *testing.T) {
+	insta := newPoolInsta(t, "alice", okTransport{})
+	key := goinsta.Passphrase("secret")
+	b, err := goinsta.EncryptConfig(insta.ExportConfig(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Crafted envelopes must fail without panicking, or deriving a key with
+	// huge scrypt parameters
+	changes := map[string]func(e map[string]interface{}){
+		"short nonce":   func(e map[string]interface{}) { e["nonce"] = "AAAA" },
+		"huge N":        func(e map[string]interface{}) { e["n"] = 1 << 30 },
+		"N not power 2": func(e map[string]interface{}) { e["n"] = 1000 },
+		"huge r":        func(e map[string]interface{}) { e["r"] = 1 << 20 },
+		"zero p":        func(e map[string]interface{}) { e["p"] = 0 },
+	}
+	for name, change := range changes {
+		t.Run(name, func(t *testing.T) {
+			var e map[string]interface{}
+			if err := json.Unmarshal(b, &e); err != nil {
+				t.Fatal(err)
+			}
+			change(e)
+			crafted, err := json.Marshal(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := goinsta.DecryptConfig(crafted, key); !errors.Is(err, goinsta.ErrWrongSessionKey) {
+				t.Fatalf("Expected ErrWrongSessionKey, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedExportFile(t *testing.T) {
+	insta := newPoolInsta(t, "alice", okTransport{})
+	key := goinsta.Passphrase("secret")
+
+	path := filepath.Join(t.TempDir(), "session.json")
+	if err := insta.ExportEncrypted(path, key); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportEncrypted(path, key, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.Account.Username != "alice" {
+		t.Fatalf("Imported wrong account %s", imported.Account.Username)
+	}
+}
+
+func TestEnvEncrypted(t *testing.T) {
+	insta := newPoolInsta(t, "alice", okTransport{})
+	enc, err := insta.ExportAsEncryptedBase64String(goinsta.Passphrase("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	line := fmt.Sprintf("INSTAGRAM_BASE64_alice=%q\n", enc)
+	if err := os.WriteFile(path, []byte(line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(goinsta.EnvPassphrase, "")
+	if _, err := goinsta.EnvLoadAccs(path); !errors.Is(err, goinsta.ErrSessionEncrypted) {
+		t.Fatalf("Expected ErrSessionEncrypted without passphrase, got: %v", err)
+	}
+
+	t.Setenv(goinsta.EnvPassphrase, "secret")
+	accs, err := goinsta.EnvLoadAccs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accs) != 1 || accs[0].Account.Username != "alice" {
+		t.Fatalf("Expected to load alice from encrypted env, got %d accounts", len(accs))
+	}
+}
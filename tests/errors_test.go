@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// statusTransport answers every request with the response provided.
+type statusTransport struct {
+	status int
+	header http.Header
+	body   string
+	gzip   bool
+}
+
+func (s *statusTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	h := http.Header{}
+	for k, v := range s.header {
+		h[k] = v
+	}
+	body := []byte(s.body)
+	if s.gzip {
+		buf := new(bytes.Buffer)
+		zw := gzip.NewWriter(buf)
+		zw.Write(body)
+		zw.Close()
+		body = buf.Bytes()
+		h.Set("Content-Encoding", "gzip")
+	}
+	return &http.Response{
+		StatusCode: s.status,
+		Status:     http.StatusText(s.status),
+		Header:     h,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+// fetchFeed fetches a user feed, without retrying, and returns the error.
+func fetchFeed(t *testing.T, transport http.RoundTripper) error {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(transport)
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{MaxAttempts: 1}))
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+	if feed.Next() {
+		t.Fatal("Expected request to fail")
+	}
+	return feed.Error()
+}
+
+func TestRateLimitError(t *testing.T) {
+	err := fetchFeed(t, &statusTransport{
+		status: 429,
+		header: http.Header{"Retry-After": []string{"7"}},
+		body:   `{"message":"Please wait a few minutes before you try again.","status":"fail"}`,
+	})
+
+	var rl goinsta.RateLimitError
+	if !errors.As(err, &rl) {
+		t.Fatalf("Expected RateLimitError, got %T: %v", err, err)
+	}
+	if !errors.Is(err, goinsta.ErrTooManyRequests) {
+		t.Fatal("Expected error to wrap ErrTooManyRequests")
+	}
+	if rl.Endpoint != "feed/user/1/" || rl.StatusCode != 429 {
+		t.Fatalf("Unexpected endpoint or status code: %s %d", rl.Endpoint, rl.StatusCode)
+	}
+	if !rl.Retryable || rl.RetryAfter != 7*time.Second {
+		t.Fatalf("Expected retry after 7s, got %v %s", rl.Retryable, rl.RetryAfter)
+	}
+	if d := goinsta.DefaultRetryPolicy().Delay(1, err, nil); d != 7*time.Second {
+		t.Fatalf("Expected retry policy to use retry hint, got %s", d)
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		sentinel error
+		check    func(err error) bool
+	}{
+		{
+			name:     "login required",
+			status:   400,
+			body:     `{"message":"login_required","status":"fail"}`,
+			sentinel: goinsta.ErrLoginRequired,
+			check:    func(err error) bool { return errors.As(err, new(goinsta.AuthError)) },
+		},
+		{
+			name:     "logged out",
+			status:   403,
+			body:     `{"message":"login_required","error_title":"You've Been Logged Out","status":"fail"}`,
+			sentinel: goinsta.ErrLoggedOut,
+			check:    func(err error) bool { return errors.As(err, new(goinsta.AuthError)) },
+		},
+		{
+			name:     "bad password",
+			status:   400,
+			body:     `{"message":"The password you entered is incorrect.","error_type":"bad_password","status":"fail"}`,
+			sentinel: goinsta.ErrBadPassword,
+			check:    func(err error) bool { return errors.As(err, new(goinsta.AuthError)) },
+		},
+		{
+			name:     "challenge",
+			status:   400,
+			body:     `{"message":"challenge_required","challenge":{"url":"https://i.instagram.com/challenge/1/abc/","api_path":"/challenge/1/abc/"},"status":"fail"}`,
+			sentinel: goinsta.ErrChallengeRequired,
+			check: func(err error) bool {
+				var c goinsta.ChallengeError
+				return errors.As(err, &c) && c.Challenge.APIPath == "/challenge/1/abc/" &&
+					c.Message == "challenge_required" &&
+					c.Error() == "challenge required: fail, challenge_required"
+			},
+		},
+		{
+			name:     "rate limited with 400",
+			status:   400,
+			body:     `{"message":"Please wait a few minutes before you try again.","status":"fail"}`,
+			sentinel: goinsta.ErrTooManyRequests,
+			check: func(err error) bool {
+				var rl goinsta.RateLimitError
+				return errors.As(err, &rl) && rl.StatusCode == 400 && goinsta.IsRetryable(err)
+			},
+		},
+		{
+			name:     "media deleted",
+			status:   400,
+			body:     `{"message":"Sorry, this media has been deleted","status":"fail"}`,
+			sentinel: goinsta.ErrMediaDeleted,
+			check:    func(err error) bool { return errors.As(err, new(goinsta.NotFoundError)) },
+		},
+		{
+			name:     "not found",
+			status:   404,
+			body:     `{"message":"User not found","status":"fail"}`,
+			sentinel: goinsta.ErrNotFound,
+			check: func(err error) bool {
+				var e goinsta.NotFoundError
+				return errors.As(err, &e) && e.Message == "User not found" && errors.As(err, new(goinsta.ErrorN))
+			},
+		},
+		{
+			name:     "feedback required",
+			status:   400,
+			body:     `{"message":"feedback_required","spam":true,"feedback_title":"Try Again Later","feedback_message":"We restrict certain activity","status":"fail"}`,
+			sentinel: goinsta.ErrFeedbackRequired,
+			check: func(err error) bool {
+				var e goinsta.FeedbackRequiredError
+				return errors.As(err, &e) && e.Spam && e.FeedbackTitle == "Try Again Later" && !goinsta.IsRetryable(err)
+			},
+		},
+		{
+			name:   "forbidden",
+			status: 403,
+			body:   `{"message":"Not authorized to view user","status":"fail"}`,
+			check: func(err error) bool {
+				var e goinsta.AuthError
+				return errors.As(err, &e) && e.StatusCode == 403 && e.Message == "Not authorized to view user"
+			},
+		},
+		{
+			name:   "service unavailable",
+			status: 503,
+			body:   `<html>Service Unavailable</html>`,
+			check: func(err error) bool {
+				var e goinsta.APIError
+				return errors.As(err, &e) && e.Retryable && e.Endpoint == "feed/user/1/" && e.StatusCode == 503 &&
+					errors.As(err, new(goinsta.Error503)) && goinsta.IsRetryable(err)
+			},
+		},
+		{
+			name:   "server error",
+			status: 500,
+			body:   `{"message":"Oops, an error occurred.","status":"fail"}`,
+			check: func(err error) bool {
+				var e goinsta.APIError
+				return errors.As(err, &e) && e.Retryable && e.StatusCode == 500 && e.Message == "Oops, an error occurred." &&
+					errors.As(err, new(goinsta.ErrorN)) && goinsta.IsRetryable(err)
+			},
+		},
+		{
+			name:   "validation",
+			status: 400,
+			body:   `{"message":"Invalid parameters","error_type":"invalid_params","status":"fail"}`,
+			check: func(err error) bool {
+				var e goinsta.ValidationError
+				return errors.As(err, &e) && e.ErrorType == "invalid_params" && errors.As(err, new(goinsta.Error400))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for _, gz := range []bool{false, true} {
+			err := fetchFeed(t, &statusTransport{status: test.status, body: test.body, gzip: gz})
+			if test.sentinel != nil && !errors.Is(err, test.sentinel) {
+				t.Errorf("%s (gzip %v): expected error to wrap %v, got %v", test.name, gz, test.sentinel, err)
+			}
+			if !test.check(err) {
+				t.Errorf("%s (gzip %v): unexpected error %T: %v", test.name, gz, err, err)
+			}
+		}
+	}
+}
+
+func TestErrorNMessage(t *testing.T) {
+	html := "<html>" + strings.Repeat("x", 1000) + "</html>"
+	err := fetchFeed(t, &statusTransport{status: 502, body: html})
+
+	var e goinsta.ErrorN
+	if !errors.As(err, &e) {
+		t.Fatalf("Expected ErrorN, got %T: %v", err, err)
+	}
+	if e.Code != 502 || e.Status != "502" || len(e.Message) > 250 {
+		t.Fatalf("Unexpected error: %d %s, message of %d bytes", e.Code, e.Status, len(e.Message))
+	}
+}
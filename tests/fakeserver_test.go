@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func fakeLogin(t *testing.T, srv *goinstatest.Server, username, password string) *goinsta.Instagram {
+	insta := goinsta.New(username, password)
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	return insta
+}
+
+// testJPEG generates a JPEG image to upload.
+func testJPEG(t *testing.T, width, height int) io.Reader {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, x%height, color.RGBA{R: 255, A: 255})
+	}
+	buf := new(bytes.Buffer)
+	if err := jpeg.Encode(buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestFakeServerLogin(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := goinsta.New("alice", "wrong")
+	srv.Connect(insta)
+	if err := insta.Login(); !errors.Is(err, goinsta.ErrBadPassword) {
+		t.Fatalf("Expected ErrBadPassword, got: %v", err)
+	}
+
+	insta = fakeLogin(t, srv, "alice", "secret")
+	if insta.Account.ID != alice.ID || insta.Account.Username != "alice" {
+		t.Fatalf("Logged in as wrong account: %d %s", insta.Account.ID, insta.Account.Username)
+	}
+	if err := insta.Logout(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFakeServerFollow(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	bob := srv.AddUser("bob", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	user, err := insta.Profiles.ByName("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.ID != bob.ID {
+		t.Fatalf("Expected user ID %d, got %d", bob.ID, user.ID)
+	}
+
+	if err := user.Follow(); err != nil {
+		t.Fatal(err)
+	}
+	if !user.Friendship.Following || !srv.IsFollowing(alice.ID, bob.ID) {
+		t.Fatal("Expected alice to follow bob")
+	}
+
+	followers := user.Followers("")
+	if !followers.Next() && followers.Error() != goinsta.ErrNoMore {
+		t.Fatal(followers.Error())
+	}
+	if len(followers.Users) != 1 || followers.Users[0].ID != alice.ID {
+		t.Fatalf("Expected alice to be bob's only follower, got %d followers", len(followers.Users))
+	}
+
+	if err := user.Unfollow(); err != nil {
+		t.Fatal(err)
+	}
+	if user.Friendship.Following || srv.IsFollowing(alice.ID, bob.ID) {
+		t.Fatal("Expected alice to no longer follow bob")
+	}
+}
+
+func TestFakeServerInbox(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.AddUser("bob", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	user, err := insta.Profiles.ByName("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conv, err := insta.Inbox.New(user, "Hi bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conv.Send("How are you?"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bob should see the thread in his inbox
+	bobInsta := fakeLogin(t, srv, "bob", "secret")
+	if err := bobInsta.Inbox.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if len(bobInsta.Inbox.Conversations) != 1 {
+		t.Fatalf("Expected 1 conversation, got %d", len(bobInsta.Inbox.Conversations))
+	}
+	c := bobInsta.Inbox.Conversations[0]
+	if c.ID != conv.ID || len(c.Items) != 2 {
+		t.Fatalf("Expected thread %s with 2 messages, got %s with %d", conv.ID, c.ID, len(c.Items))
+	}
+	if c.Items[0].Text != "How are you?" || c.Items[0].UserID != alice.ID {
+		t.Fatalf("Unexpected last message: %+v", c.Items[0])
+	}
+	if err := c.Send("Fine!"); err != nil {
+		t.Fatal(err)
+	}
+
+	threads := srv.Threads(alice.ID)
+	if len(threads) != 1 || len(threads[0].Messages) != 3 {
+		t.Fatalf("Expected 1 thread with 3 messages on the server")
+	}
+}
+
+func TestFakeServerUpload(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	item, err := insta.Upload(
+		&goinsta.UploadOptions{
+			File:    testJPEG(t, 320, 240),
+			Caption: "Hello from the fake server",
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Caption.Text != "Hello from the fake server" {
+		t.Fatalf("Unexpected caption %q", item.Caption.Text)
+	}
+
+	media := srv.Media(alice.ID)
+	if len(media) != 1 || media[0].Width != 320 || media[0].Height != 240 {
+		t.Fatalf("Expected one 320x240 post on the server, got %d", len(media))
+	}
+
+	feed := insta.Account.Feed()
+	if !feed.Next() && feed.Error() != goinsta.ErrNoMore {
+		t.Fatal(feed.Error())
+	}
+	if len(feed.Items) != 1 || feed.Items[0].Pk != media[0].ID {
+		t.Fatalf("Expected uploaded post in the user feed, got %d items", len(feed.Items))
+	}
+}
@@ -1,15 +1,12 @@
 package tests
 
 import (
-	"math/rand"
 	"testing"
 	"time"
-
-	"github.com/Davincible/goinsta/v3"
 )
 
 func TestFeedUser(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -40,14 +37,14 @@ func TestFeedUser(t *testing.T) {
 		if i == 5 {
 			break
 		}
-		time.Sleep(time.Duration(rand.Intn(10)) * time.Second)
+		pause(10)
 	}
 
 	t.Logf("Gathered %d posts, %d on last request\n", len(feed.Items), feed.NumResults)
 }
 
 func TestFeedDiscover(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -68,14 +65,14 @@ outside:
 		if i == 5 {
 			break outside
 		}
-		time.Sleep(time.Duration(rand.Intn(10)) * time.Second)
+		pause(10)
 	}
 
 	t.Logf("Gathered %d posts, %d on last request\n", len(feed.Items), feed.NumResults)
 }
 
 func TestFeedTagLike(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -111,7 +108,7 @@ func TestFeedTagLike(t *testing.T) {
 }
 
 func TestFeedTagNextOld(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -140,7 +137,7 @@ func TestFeedTagNextOld(t *testing.T) {
 }
 
 func TestFeedTagNext(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -175,7 +172,7 @@ func TestFeedTagNext(t *testing.T) {
 }
 
 func TestFeedTagNextRecent(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+type ctxKey struct{}
+
+// recordingInstrumentation keeps all calls reported.
+type recordingInstrumentation struct {
+	t       *testing.T
+	started int
+	calls   []goinsta.APICall
+}
+
+func (r *recordingInstrumentation) RequestStarted(ctx context.Context, call *goinsta.APICall) context.Context {
+	r.started++
+	return context.WithValue(ctx, ctxKey{}, r.started)
+}
+
+func (r *recordingInstrumentation) RequestFinished(ctx context.Context, call *goinsta.APICall) {
+	if ctx.Value(ctxKey{}) != len(r.calls)+1 {
+		r.t.Errorf("Expected context returned by RequestStarted, got value %v", ctx.Value(ctxKey{}))
+	}
+	r.calls = append(r.calls, *call)
+}
+
+func TestInstrument(t *testing.T) {
+	inst := &recordingInstrumentation{t: t}
+	insta := newRetryInsta(t, &flakyTransport{n: 2, status: 429})
+	insta.Use(goinsta.Instrument(inst))
+	insta.Account = &goinsta.Account{ID: 1}
+
+	user := insta.NewUser()
+	user.ID = 123
+	if feed := user.Feed(); !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+	if len(inst.calls) != 3 {
+		t.Fatalf("Expected 3 calls, got %d", len(inst.calls))
+	}
+	for i, call := range inst.calls {
+		if call.Endpoint != "feed/user/%d/" || call.Method != "GET" || call.Attempt != i+1 {
+			t.Fatalf("Unexpected call: %+v", call)
+		}
+	}
+	if inst.calls[0].ErrorKind != "too_many_requests" || inst.calls[0].StatusCode != 429 {
+		t.Fatalf("Expected too_many_requests, got %+v", inst.calls[0])
+	}
+	if last := inst.calls[2]; last.Error != nil || last.StatusCode != 200 || last.BytesReceived == 0 {
+		t.Fatalf("Expected successful call, got %+v", last)
+	}
+
+	inst.calls, inst.started = nil, 0
+	if err := user.Follow(); err != nil {
+		t.Fatal(err)
+	}
+	if len(inst.calls) != 1 || inst.calls[0].Endpoint != "friendships/create/%d/" || inst.calls[0].BytesSent == 0 {
+		t.Fatalf("Unexpected calls: %+v", inst.calls)
+	}
+
+	// Aborted requests should be reported too
+	errBlocked := errors.New("blocked")
+	insta.Use(goinsta.Middleware{
+		BeforeSend: func(*goinsta.Request) error { return errBlocked },
+	})
+	inst.calls, inst.started = nil, 0
+	if err := user.Follow(); !errors.Is(err, errBlocked) {
+		t.Fatalf("Expected request to be blocked, got: %v", err)
+	}
+	if len(inst.calls) != 1 || inst.calls[0].ErrorKind != "other" {
+		t.Fatalf("Unexpected calls: %+v", inst.calls)
+	}
+}
+
+func TestEndpointTemplate(t *testing.T) {
+	tests := map[string]string{
+		"feed/user/123/":                             "feed/user/%d/",
+		"feed/user/123/story/":                       "feed/user/%d/story/",
+		"media/123_456/comment/789/delete/":          "media/%s/comment/%s/delete/",
+		"direct_v2/threads/340282366841710300/":      "direct_v2/threads/%s/",
+		"feed/timeline/":                             "feed/timeline/",
+		"media/configure_to_story/?video=1":          "media/configure_to_story/",
+		"challenge/123456/AbCdEf/":                   "challenge/%s/AbCdEf/",
+		"rupload_igphoto/1663409281_0_8364539124":    "rupload_igphoto/%s",
+		"friendships/create/42/":                     "friendships/create/%d/",
+		"friendships/show_many/":                     "friendships/show_many/",
+		"tags/golang/sections/":                      "tags/%s/sections/",
+		"live/17866843576/get_comment/":              "live/%d/get_comment/",
+		"igtv/series/all_user_series/1234567/":       "igtv/series/all_user_series/%d/",
+		"direct_v2/threads/1234/items/5678/seen/":    "direct_v2/threads/%s/items/%s/seen/",
+		"users/goinsta/usernameinfo/":                "users/%s/usernameinfo/",
+		"accounts/current_user/":                     "accounts/current_user/",
+		"collections/17851999/edit/":                 "collections/%s/edit/",
+		"highlights/123/highlights_tray/":            "highlights/%d/highlights_tray/",
+		"feed/collection/17851999/posts/":            "feed/collection/%s/posts/",
+		"media/2916538726_1423/info/":                "media/%s/info/",
+		"usertags/987/feed/":                         "usertags/%d/feed/",
+		"friendships/987/followers/":                 "friendships/%d/followers/",
+		"direct_v2/threads/1234/get_items/":          "direct_v2/threads/%s/get_items/",
+		"locations/213385402/sections/":              "locations/%d/sections/",
+		"feed/location/213385402/":                   "feed/location/%d/",
+		"media/2916538726_1423/comment/bulk_delete/": "media/%s/comment/bulk_delete/",
+	}
+	for endpoint, want := range tests {
+		if got := goinsta.EndpointTemplate(endpoint); got != want {
+			t.Errorf("EndpointTemplate(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+// addPosts adds n posts of user, taken a day apart, the newest yesterday.
+func addPosts(srv *goinstatest.Server, user int64, n int) []*goinstatest.Media {
+	var posts []*goinstatest.Media
+	now := time.Now().Truncate(time.Second)
+	for i := n; i > 0; i-- {
+		posts = append(posts, srv.AddMedia(user, goinstatest.Media{
+			Caption: fmt.Sprintf("post %d", n-i+1),
+			TakenAt: now.Add(-time.Duration(i) * 24 * time.Hour),
+		}))
+	}
+	return posts
+}
+
+func TestIteratorFeed(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.SetPageSize(2)
+	addPosts(srv, alice.ID, 5)
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	var captions []string
+	it := insta.Account.Feed().Iter()
+	for item := range it.All() {
+		captions = append(captions, item.Caption.Text)
+	}
+	if err := it.Error(); err != nil {
+		t.Fatal(err)
+	}
+	expected := "[post 5 post 4 post 3 post 2 post 1]"
+	if fmt.Sprint(captions) != expected {
+		t.Fatalf("Expected %s, got %v", expected, captions)
+	}
+
+	// Items already fetched come first
+	feed := insta.Account.Feed()
+	if !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+	items, err := feed.Iter().Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("Expected 5 items, got %d", len(items))
+	}
+}
+
+func TestIteratorOptions(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+	srv.SetPageSize(2)
+	posts := addPosts(srv, alice.ID, 5)
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	items, err := insta.Account.Feed().Iter(goinsta.IterOptions{Limit: 3}).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got %d", len(items))
+	}
+
+	// Stops at the first post older than the third post
+	items, err = insta.Account.Feed().Iter(goinsta.IterOptions{StopAt: posts[2].TakenAt}).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[2].Caption.Text != "post 3" {
+		t.Fatalf("Expected to stop after post 3, got %d items", len(items))
+	}
+
+	// Breaking out of the loop doesn't fetch more pages
+	feed := insta.Account.Feed()
+	for range feed.Iter().All() {
+		break
+	}
+	if len(feed.Items) != 2 {
+		t.Fatalf("Expected only the first page to be fetched, got %d items", len(feed.Items))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it := insta.Account.Feed().Iter(goinsta.IterOptions{Context: ctx})
+	if it.Next() || !errors.Is(it.Error(), context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", it.Error())
+	}
+}
+
+func TestIteratorUsers(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+	srv.SetPageSize(2)
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	for i := 0; i < 5; i++ {
+		srv.AddUser(fmt.Sprintf("user%d", i), "secret")
+		u := fakeLogin(t, srv, fmt.Sprintf("user%d", i), "secret")
+		profile, err := u.Profiles.ByName("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := profile.Follow(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	users, err := insta.Account.Followers("").Iter().Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 5 {
+		t.Fatalf("Expected 5 followers, got %d", len(users))
+	}
+	seen := map[int64]bool{}
+	for _, u := range users {
+		if seen[u.ID] {
+			t.Fatalf("User %s iterated twice", u.Username)
+		}
+		seen[u.ID] = true
+	}
+}
+
+func TestIteratorCommentsNoMedia(t *testing.T) {
+	it := (&goinsta.Comments{}).Iter()
+	if it.Next() {
+		t.Fatal("Expected no comments without media")
+	}
+	if !errors.Is(it.Error(), goinsta.ErrInstaNotDefined) {
+		t.Fatalf("Expected ErrInstaNotDefined, got: %v", it.Error())
+	}
+}
+
+func TestIteratorDiscoverSlices(t *testing.T) {
+	var sec goinsta.DiscoverSectionalItem
+	medias := make([]goinsta.DiscoverMediaItem, 1, 4)
+	medias[0].Media.ID = "media"
+	sec.LayoutContent.Medias = medias
+	sec.LayoutContent.FillItems = []goinsta.DiscoverMediaItem{{Media: goinsta.Item{ID: "fill"}}}
+	disc := &goinsta.Discover{Items: []goinsta.DiscoverSectionalItem{sec}}
+
+	it := disc.Iter(goinsta.IterOptions{Limit: 2})
+	items, err := it.Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != "media" || items[1].ID != "fill" {
+		t.Fatalf("Expected the media and fill item, got %d items", len(items))
+	}
+	// The spare capacity of the medias of the section must not be written to
+	if spare := medias[:2][1]; spare.Media.ID != nil {
+		t.Fatalf("Medias have been written to: %v", spare.Media.ID)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// recordHandler is a slog.Handler that keeps all records logged.
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordHandler) WithGroup(string) slog.Handler { return h }
+
+func (h *recordHandler) find(msg string) []map[string]slog.Value {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var found []map[string]slog.Value
+	for _, r := range h.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := map[string]slog.Value{}
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value
+			return true
+		})
+		found = append(found, attrs)
+	}
+	return found
+}
+
+func TestLogger(t *testing.T) {
+	h := &recordHandler{}
+	insta := newRetryInsta(t, &flakyTransport{n: 1, status: 500})
+	insta.SetLogger(slog.New(h))
+
+	user := insta.NewUser()
+	user.ID = 1
+	if !user.Feed().Next() {
+		t.Fatal("Expected request to succeed after retrying")
+	}
+
+	requests := h.find("Request")
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 request records, got %d", len(requests))
+	}
+	for i, r := range requests {
+		if r["endpoint"].String() != "feed/user/1/" {
+			t.Fatalf("Unexpected endpoint %s", r["endpoint"])
+		}
+		if r["attempt"].Int64() != int64(i+1) {
+			t.Fatalf("Expected attempt %d, got %s", i+1, r["attempt"])
+		}
+		if r["latency"].Kind() != slog.KindDuration {
+			t.Fatalf("Expected latency to be a duration, got %s", r["latency"].Kind())
+		}
+		if _, ok := r["body"]; ok {
+			t.Fatal("Expected no response body to be logged if Debug is false")
+		}
+	}
+	if requests[0]["status"].Int64() != 500 || requests[1]["status"].Int64() != 200 {
+		t.Fatalf("Unexpected status codes %s, %s", requests[0]["status"], requests[1]["status"])
+	}
+
+	retries := h.find("Request failed, retrying")
+	if len(retries) != 1 || retries[0]["delay"].Kind() != slog.KindDuration {
+		t.Fatalf("Expected 1 retry record with a delay, got %d", len(retries))
+	}
+}
+
+func TestLoggerHandlers(t *testing.T) {
+	var warnings, debug []string
+	insta := newRetryInsta(t, &flakyTransport{n: 1, status: 500})
+	insta.SetWarnHandler(func(args ...interface{}) {
+		warnings = append(warnings, fmt.Sprint(args...))
+	})
+	insta.SetDebugHandler(func(args ...interface{}) {
+		debug = append(debug, fmt.Sprint(args...))
+	})
+
+	user := insta.NewUser()
+	user.ID = 1
+	if !user.Feed().Next() {
+		t.Fatal("Expected request to succeed after retrying")
+	}
+
+	if len(warnings) != 1 {
+		t.Fatalf("Expected 1 warning, got %d", len(warnings))
+	}
+	if !strings.HasPrefix(warnings[0], "Request failed, retrying endpoint=feed/user/1/ attempt=1") {
+		t.Fatalf("Unexpected warning: %s", warnings[0])
+	}
+	if len(debug) != 0 {
+		t.Fatalf("Expected no debug messages if Debug is false, got %d", len(debug))
+	}
+
+	insta.Debug = true
+	insta.Account = &goinsta.Account{ID: 42}
+	if feed := user.Feed(); !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+	if len(debug) != 1 {
+		t.Fatalf("Expected 1 debug message, got %d", len(debug))
+	}
+	for _, attr := range []string{"user_id=42", "status=200", "latency=", `body="{\"status\":\"ok\"}"`} {
+		if !strings.Contains(debug[0], attr) {
+			t.Fatalf("Expected %s in debug message: %s", attr, debug[0])
+		}
+	}
+}
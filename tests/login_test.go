@@ -8,7 +8,7 @@ import (
 
 func TestImportAccount(t *testing.T) {
 	// Test Import
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -22,7 +22,7 @@ func TestImportAccount(t *testing.T) {
 
 func TestLogin(t *testing.T) {
 	// Test Login
-	user, pass, err := goinsta.EnvRandLogin()
+	insta, user, err := testLogin(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -31,7 +31,6 @@ func TestLogin(t *testing.T) {
 		t.Skip()
 	}
 
-	insta := goinsta.New(user, pass)
 	if err = insta.Login(); err != nil {
 		t.Fatal(err)
 	}
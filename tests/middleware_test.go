@@ -0,0 +1,155 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// captureTransport keeps the last request sent.
+type captureTransport struct {
+	req *http.Request
+}
+
+func (c *captureTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.req = r
+	return okTransport{}.RoundTrip(r)
+}
+
+// countingWrapper counts the number of times it has been called.
+type countingWrapper struct {
+	calls int32
+}
+
+func (w *countingWrapper) GoInstaWrapper(o *goinsta.ReqWrapperArgs) ([]byte, http.Header, error) {
+	atomic.AddInt32(&w.calls, 1)
+	return o.Body, o.Headers, o.Error
+}
+
+func TestMiddlewareBeforeSend(t *testing.T) {
+	transport := &captureTransport{}
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(transport)
+
+	var endpoints []string
+	insta.Use(
+		goinsta.Middleware{
+			BeforeSend: func(r *goinsta.Request) error {
+				r.Header.Set("X-Audit", "1")
+				endpoints = append(endpoints, r.Endpoint)
+				return nil
+			},
+		},
+		goinsta.Middleware{
+			BeforeSend: func(r *goinsta.Request) error {
+				q := r.URL.Query()
+				q.Set("audit", r.Header.Get("X-Audit"))
+				r.URL.RawQuery = q.Encode()
+				return nil
+			},
+		},
+	)
+
+	user := insta.NewUser()
+	user.ID = 1
+	if feed := user.Feed(); !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+	if len(endpoints) != 1 || endpoints[0] != "feed/user/1/" {
+		t.Fatalf("Unexpected endpoints %v", endpoints)
+	}
+	if transport.req.Header.Get("X-Audit") != "1" || transport.req.URL.Query().Get("audit") != "1" {
+		t.Fatalf("Expected header and query set by middleware, got %s", transport.req.URL)
+	}
+
+	// An error returned by a hook aborts the request
+	errBlocked := errors.New("blocked")
+	insta.Use(goinsta.Middleware{
+		BeforeSend: func(r *goinsta.Request) error {
+			return errBlocked
+		},
+	})
+	transport.req = nil
+	if feed := user.Feed(); feed.Next() || !errors.Is(feed.Error(), errBlocked) {
+		t.Fatalf("Expected request to be blocked, got: %v", feed.Error())
+	}
+	if transport.req != nil {
+		t.Fatal("Expected no request to be sent")
+	}
+}
+
+func TestMiddlewareAfterReceive(t *testing.T) {
+	insta := newRetryInsta(t, &flakyTransport{n: 2, status: 500})
+
+	var statuses, attempts []int
+	insta.Use(goinsta.Middleware{
+		AfterReceive: func(r *goinsta.Response) {
+			statuses = append(statuses, r.StatusCode)
+			attempts = append(attempts, r.Request.Attempt)
+		},
+	})
+
+	user := insta.NewUser()
+	user.ID = 1
+	if feed := user.Feed(); !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+
+	// The default wrapper should still retry the request
+	if len(statuses) != 3 || statuses[0] != 500 || statuses[1] != 500 || statuses[2] != 200 {
+		t.Fatalf("Unexpected status codes %v", statuses)
+	}
+	if attempts[0] != 1 || attempts[1] != 2 || attempts[2] != 3 {
+		t.Fatalf("Unexpected attempts %v", attempts)
+	}
+}
+
+func TestMiddlewareOnError(t *testing.T) {
+	transport := &flakyTransport{n: 1, status: 500}
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(transport)
+	wrapper := &countingWrapper{}
+	insta.SetWrapper(wrapper)
+
+	var handled []error
+	insta.Use(
+		goinsta.Middleware{
+			// Passes the error on
+			OnError: func(o *goinsta.ReqWrapperArgs) ([]byte, http.Header, error) {
+				handled = append(handled, o.Error)
+				return o.Body, o.Headers, o.Error
+			},
+		},
+		goinsta.Middleware{
+			OnError: func(o *goinsta.ReqWrapperArgs) ([]byte, http.Header, error) {
+				return o.RetryRequest()
+			},
+		},
+		goinsta.Middleware{
+			OnError: func(o *goinsta.ReqWrapperArgs) ([]byte, http.Header, error) {
+				t.Fatal("Expected error handlers to be skipped after a retry")
+				return o.Body, o.Headers, o.Error
+			},
+		},
+	)
+
+	user := insta.NewUser()
+	user.ID = 1
+	if feed := user.Feed(); !feed.Next() {
+		t.Fatal(feed.Error())
+	}
+	if len(handled) != 1 || handled[0] == nil {
+		t.Fatalf("Expected one error to be handled, got %v", handled)
+	}
+	if transport.calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", transport.calls)
+	}
+
+	// The wrapper is only called for the retried request, that succeeded
+	if wrapper.calls != 1 {
+		t.Fatalf("Expected wrapper to be called once, got %d", wrapper.calls)
+	}
+}
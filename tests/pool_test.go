@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+func newPoolInsta(t *testing.T, username string, transport http.RoundTripper) *goinsta.Instagram {
+	insta := goinsta.New(username, "password")
+	insta.SetHTTPTransport(transport)
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{MaxAttempts: 1}))
+	insta.Account = &goinsta.Account{ID: 1, Username: username}
+	return insta
+}
+
+func leaseAndFetch(t *testing.T, pool *goinsta.AccountPool) string {
+	lease, err := pool.Lease(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Release()
+
+	user := lease.Insta.NewUser()
+	user.ID = 1
+	user.Feed().Next()
+	return lease.Insta.Account.Username
+}
+
+func TestAccountPool(t *testing.T) {
+	pool := goinsta.NewAccountPool(
+		newPoolInsta(t, "healthy", okTransport{}),
+		newPoolInsta(t, "limited", &statusTransport{
+			status: 429,
+			header: http.Header{"Retry-After": []string{"3600"}},
+		}),
+		newPoolInsta(t, "loggedout", &statusTransport{
+			status: 400,
+			body:   `{"message":"login_required","status":"fail"}`,
+		}),
+	)
+
+	// Accounts are leased in turn
+	for _, want := range []string{"healthy", "limited", "loggedout"} {
+		if got := leaseAndFetch(t, pool); got != want {
+			t.Fatalf("Expected to lease %s, got %s", want, got)
+		}
+	}
+
+	states := map[string]goinsta.AccountStatus{}
+	for _, s := range pool.Accounts() {
+		states[s.Username] = s
+	}
+	if states["healthy"].State != goinsta.AccountHealthy {
+		t.Fatalf("Expected healthy account, got %s", states["healthy"].State)
+	}
+	if s := states["limited"]; s.State != goinsta.AccountRateLimited || time.Until(s.Until) < 59*time.Minute {
+		t.Fatalf("Expected account to be rate limited for an hour, got %s until %s", s.State, s.Until)
+	}
+	if s := states["loggedout"]; s.State != goinsta.AccountLoggedOut || s.LastError == "" {
+		t.Fatalf("Expected logged out account, got %s (%s)", s.State, s.LastError)
+	}
+
+	// Only the healthy account should be leased from now on
+	for i := 0; i < 3; i++ {
+		if got := leaseAndFetch(t, pool); got != "healthy" {
+			t.Fatalf("Expected to lease the healthy account, got %s", got)
+		}
+	}
+
+	// While it's leased, wait for it to be released
+	lease, err := pool.Lease(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Lease(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected lease to time out, got: %v", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		lease.Release()
+	}()
+	lease2, err := pool.Lease(context.Background())
+	if err != nil || lease2.Insta != lease.Insta {
+		t.Fatalf("Expected to lease the released account, got: %v", err)
+	}
+	lease2.Release()
+
+	// The state should survive an export and import
+	buf := new(bytes.Buffer)
+	if err := pool.ExportIO(buf); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := goinsta.ImportAccountPoolReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range imported.Accounts() {
+		want := states[s.Username]
+		if s.State != want.State || !s.Until.Equal(want.Until) {
+			t.Fatalf("Expected %s to be %s after import, got %s", s.Username, want.State, s.State)
+		}
+	}
+
+	// Put the logged out account back in rotation
+	if !pool.SetState("loggedout", goinsta.AccountHealthy) {
+		t.Fatal("Expected account to be found")
+	}
+	if got := leaseAndFetch(t, pool); got != "loggedout" {
+		t.Fatalf("Expected to lease the reset account, got %s", got)
+	}
+}
+
+func TestAccountPoolExhausted(t *testing.T) {
+	pool := goinsta.NewAccountPool(
+		newPoolInsta(t, "challenged", &statusTransport{
+			status: 400,
+			body:   `{"message":"challenge_required","challenge":{"api_path":"/challenge/1/a/"},"status":"fail"}`,
+		}),
+	)
+	leaseAndFetch(t, pool)
+
+	if _, err := pool.Lease(context.Background()); !errors.Is(err, goinsta.ErrNoHealthyAccounts) {
+		t.Fatalf("Expected ErrNoHealthyAccounts, got: %v", err)
+	}
+}
@@ -1,9 +1,19 @@
 package tests
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
 )
 
 func TestProfileVisit(t *testing.T) {
@@ -65,3 +75,146 @@ func TestProfilesBlocked(t *testing.T) {
 
 	t.Logf("Foud %d blocked users", len(blocked))
 }
+
+// concurrencyLimiter records the number of requests waiting at once.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	inFlight int
+	max      int
+	calls    int
+}
+
+func (l *concurrencyLimiter) Wait(ctx context.Context, endpoint string) error {
+	l.mu.Lock()
+	l.inFlight++
+	l.calls++
+	if l.inFlight > l.max {
+		l.max = l.inFlight
+	}
+	l.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	l.mu.Lock()
+	l.inFlight--
+	l.mu.Unlock()
+	return nil
+}
+
+func TestProfilesByIDs(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+	var ids []int64
+	for i := 0; i < 10; i++ {
+		ids = append(ids, srv.AddUser(fmt.Sprintf("user%d", i), "secret").ID)
+	}
+	// Duplicates, and a user that doesn't exist
+	ids = append(ids, ids[0], ids[1], 999999)
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	limiter := &concurrencyLimiter{}
+	insta.SetRateLimiter(limiter)
+
+	results := insta.Profiles.ByIDs(ids, goinsta.BatchOptions{Workers: 3})
+	if len(results) != 11 {
+		t.Fatalf("Expected 11 results, got %d", len(results))
+	}
+	for i, r := range results[:10] {
+		if r.Err != nil {
+			t.Fatalf("Failed to look up %s: %v", r.Key, r.Err)
+		}
+		if r.User.ID != ids[i] || r.User.Username != fmt.Sprintf("user%d", i) {
+			t.Fatalf("Expected user%d, got %s", i, r.User.Username)
+		}
+	}
+	if r := results[10]; r.Key != "999999" || r.Err == nil || r.User != nil {
+		t.Fatalf("Expected unknown user to fail, got %+v", r)
+	}
+
+	if limiter.calls != 11 {
+		t.Fatalf("Expected 11 requests, got %d", limiter.calls)
+	}
+	if limiter.max > 3 {
+		t.Fatalf("Expected at most 3 concurrent requests, got %d", limiter.max)
+	}
+}
+
+// throttlingTransport answers each user lookup with a 429 the first time,
+// and with the user the next time.
+type throttlingTransport struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (tr *throttlingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	m := regexp.MustCompile(`users/(\d+)/info/`).FindStringSubmatch(r.URL.Path)
+	if m == nil {
+		return okTransport{}.RoundTrip(r)
+	}
+
+	tr.mu.Lock()
+	first := !tr.seen[m[1]]
+	tr.seen[m[1]] = true
+	tr.mu.Unlock()
+
+	status, body := http.StatusOK, fmt.Sprintf(`{"user":{"pk":%s,"username":"user%s"},"status":"ok"}`, m[1], m[1])
+	if first {
+		status, body = http.StatusTooManyRequests, `{"message":"Please wait a few minutes before you try again.","status":"fail"}`
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func TestProfilesByIDsRetry(t *testing.T) {
+	insta := newRetryInsta(t, &throttlingTransport{seen: map[string]bool{}})
+	// Wait long enough for the retries of the workers to overlap
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{
+		MaxAttempts:    3,
+		RateLimitDelay: 20 * time.Millisecond,
+	}))
+
+	var ids []int64
+	for i := int64(1); i <= 16; i++ {
+		ids = append(ids, i)
+	}
+	results := insta.Profiles.ByIDs(ids, goinsta.BatchOptions{Workers: 8})
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("Failed to look up %s: %v", r.Key, r.Err)
+		}
+		if r.User.ID != ids[i] || r.User.Username != "user"+r.Key {
+			t.Fatalf("Looked up %s, got user %d", r.Key, r.User.ID)
+		}
+	}
+}
+
+func TestProfilesByNames(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+	srv.AddUser("bob", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	results := insta.Profiles.ByNames([]string{"bob", "alice", "Bob"})
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	for i, name := range []string{"bob", "alice"} {
+		if r := results[i]; r.Err != nil || r.User.Username != name {
+			t.Fatalf("Expected %s, got %+v", name, r)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = insta.Profiles.ByNames([]string{"bob"}, goinsta.BatchOptions{Context: ctx})
+	if !errors.Is(results[0].Err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got: %v", results[0].Err)
+	}
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// okTransport answers every request with an empty, successful response.
+type okTransport struct{}
+
+func (okTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"status":"ok"}`)),
+		Request:    r,
+	}, nil
+}
+
+type recordingLimiter struct {
+	mu        sync.Mutex
+	endpoints []string
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context, endpoint string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.endpoints = append(l.endpoints, endpoint)
+	return nil
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	limiter := goinsta.NewTokenBucketLimiter(
+		goinsta.EndpointLimit{
+			Pattern:  "friendships/create/*",
+			Requests: 1,
+			Interval: 200 * time.Millisecond,
+		},
+	)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(ctx, "friendships/create/123/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 350*time.Millisecond {
+		t.Fatalf("Expected limiter to wait for the budget to refill, took %s", d)
+	}
+
+	// Endpoints without a group should never wait
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		if err := limiter.Wait(ctx, "feed/timeline/"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 50*time.Millisecond {
+		t.Fatalf("Unlimited endpoint was delayed for %s", d)
+	}
+
+	// Waiting must stop when the context is cancelled
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	limiter.Wait(ctx, "friendships/create/123/")
+	if err := limiter.Wait(ctx, "friendships/create/123/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestSetRateLimiter(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(okTransport{})
+
+	limiter := &recordingLimiter{}
+	insta.SetRateLimiter(limiter)
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+	feed.Next()
+
+	if len(limiter.endpoints) != 1 || limiter.endpoints[0] != "feed/user/1/" {
+		t.Fatalf("Rate limiter was not consulted correctly: %v", limiter.endpoints)
+	}
+}
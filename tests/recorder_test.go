@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// authTransport hands out a session token, like Instagram does on login.
+type authTransport struct{}
+
+func (authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := okTransport{}.RoundTrip(r)
+	resp.Header.Set("Ig-Set-Authorization", "Bearer IGT:2:secret-session-token")
+	resp.Header.Set("Set-Cookie", "sessionid=secret-cookie; Path=/")
+	return resp, err
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, err := goinsta.NewRecorder(path, goinsta.RecorderRecord, authTransport{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(rec)
+
+	// The second request is sent with the session token
+	for _, id := range []int64{1, 2} {
+		user := insta.NewUser()
+		user.ID = id
+		if feed := user.Feed(); !feed.Next() {
+			t.Fatal(feed.Error())
+		}
+	}
+
+	body := strings.NewReader("enc_password=%23PWD_INSTAGRAM%3A4%3A1650000000%3Asecret-password")
+	req, _ := http.NewRequest(http.MethodPost, "https://i.instagram.com/api/v1/accounts/login/", body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := rec.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-session-token", "secret-cookie", "secret-password"} {
+		if strings.Contains(string(b), secret) {
+			t.Fatalf("Cassette contains secret %q", secret)
+		}
+	}
+	if !strings.Contains(string(b), "REDACTED") {
+		t.Fatal("Cassette has not been scrubbed")
+	}
+
+	// Replay
+	rec, err = goinsta.NewRecorder(path, goinsta.RecorderReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insta = goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(rec)
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{MaxAttempts: 1}))
+
+	for _, id := range []int64{2, 1} {
+		user := insta.NewUser()
+		user.ID = id
+		if feed := user.Feed(); !feed.Next() {
+			t.Fatal(feed.Error())
+		}
+	}
+	if n := rec.Unused(); n != 1 {
+		t.Fatalf("Expected 1 unused interaction, got %d", n)
+	}
+
+	// No recorded interactions are left for user feeds
+	user := insta.NewUser()
+	user.ID = 3
+	if feed := user.Feed(); feed.Next() {
+		t.Fatal("Expected replay to fail without recorded interactions")
+	}
+}
+
+func TestRecorderScrubFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	rec, err := goinsta.NewRecorder(path, goinsta.RecorderRecord, &statusTransport{
+		status: 200,
+		body:   `{"totp_seed":"SECRETSEED","totp_seed_id":1,"backup_codes":["secret-code-1","secret-code-2"],"extra":{"recovery_token":"secret-token"},"status":"ok"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.ScrubFields("recovery_token")
+
+	req, _ := http.NewRequest(http.MethodPost, "https://i.instagram.com/api/v1/accounts/generate_two_factor_totp_key/", nil)
+	if _, err := rec.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"SECRETSEED", "secret-code", "secret-token"} {
+		if strings.Contains(string(b), secret) {
+			t.Fatalf("Cassette contains secret %q", secret)
+		}
+	}
+	if !strings.Contains(string(b), `totp_seed_id\":1`) {
+		t.Fatal("Expected other fields to be kept")
+	}
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func TestValidate(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	if err := insta.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExpireSessions(alice.ID)
+	if err := insta.Validate(); !errors.Is(err, goinsta.ErrLoggedOut) {
+		t.Fatalf("Expected ErrLoggedOut, got: %v", err)
+	}
+}
+
+func TestAutoRelogin(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	store, err := goinsta.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := insta.SetSessionStore(store); err != nil {
+		t.Fatal(err)
+	}
+	insta.SetAutoRelogin("secret")
+
+	var logins int32
+	insta.Use(goinsta.Middleware{
+		BeforeSend: func(r *goinsta.Request) error {
+			if r.Endpoint == "accounts/login/" {
+				atomic.AddInt32(&logins, 1)
+			}
+			return nil
+		},
+	})
+
+	old, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired session should be refreshed, and the request replayed
+	srv.ExpireSessions(alice.ID)
+	if err := insta.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&logins); n != 1 {
+		t.Fatalf("Expected to login once, logged in %d times", n)
+	}
+	if insta.Account.Username != "alice" {
+		t.Fatalf("Expected account to be synced, got %s", insta.Account.Username)
+	}
+
+	// And the refreshed session saved
+	config, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HeaderOptions["Authorization"] == old.HeaderOptions["Authorization"] {
+		t.Fatal("Expected refreshed session to be saved")
+	}
+
+	// A valid session doesn't login again
+	if err := insta.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&logins); n != 1 {
+		t.Fatalf("Expected not to login again, logged in %d times", n)
+	}
+}
+
+func TestAutoReloginFailed(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	insta.SetAutoRelogin("changed")
+
+	srv.ExpireSessions(alice.ID)
+	err := insta.Validate()
+	if !errors.Is(err, goinsta.ErrLoggedOut) {
+		t.Fatalf("Expected ErrLoggedOut, got: %v", err)
+	}
+	if !errors.Is(err, goinsta.ErrBadPassword) {
+		t.Fatalf("Expected the failed login to be reported, got: %v", err)
+	}
+}
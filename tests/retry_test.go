@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+)
+
+// flakyTransport fails the first n requests with the status code provided,
+// or with a transport error if the status code is 0. The transport error is
+// err, or a connection reset if nil.
+type flakyTransport struct {
+	n      int32
+	status int
+	err    error
+	calls  int32
+}
+
+func (f *flakyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if atomic.AddInt32(&f.calls, 1) <= f.n {
+		if f.status == 0 && f.err != nil {
+			return nil, f.err
+		}
+		if f.status == 0 {
+			return nil, errors.New("connection reset by peer")
+		}
+		return &http.Response{
+			StatusCode: f.status,
+			Status:     http.StatusText(f.status),
+			Header:     http.Header{"Retry-After": []string{"0"}},
+			Body:       io.NopCloser(strings.NewReader(`{"status":"fail"}`)),
+			Request:    r,
+		}, nil
+	}
+	return okTransport{}.RoundTrip(r)
+}
+
+func newRetryInsta(t *testing.T, transport http.RoundTripper) *goinsta.Instagram {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(transport)
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    10 * time.Millisecond,
+	}))
+	return insta
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	for _, status := range []int{500, 502, 503} {
+		transport := &flakyTransport{n: 2, status: status}
+		insta := newRetryInsta(t, transport)
+
+		user := insta.NewUser()
+		user.ID = 1
+		feed := user.Feed()
+		if !feed.Next() {
+			t.Fatalf("Status %d: expected request to succeed after retries, got: %v", status, feed.Error())
+		}
+		if transport.calls != 3 {
+			t.Fatalf("Status %d: expected 3 calls, got %d", status, transport.calls)
+		}
+	}
+}
+
+func TestRetryTransportErrors(t *testing.T) {
+	transport := &flakyTransport{n: 1}
+	insta := newRetryInsta(t, transport)
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+	if !feed.Next() {
+		t.Fatalf("Expected request to succeed after retry, got: %v", feed.Error())
+	}
+	if transport.calls != 2 {
+		t.Fatalf("Expected 2 calls, got %d", transport.calls)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	transport := &flakyTransport{n: 10, status: 500}
+	insta := newRetryInsta(t, transport)
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+	if feed.Next() {
+		t.Fatal("Expected request to fail")
+	}
+	if transport.calls != 3 {
+		t.Fatalf("Expected 3 calls, got %d", transport.calls)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	transport := &flakyTransport{n: 10, status: 404}
+	insta := newRetryInsta(t, transport)
+
+	user := insta.NewUser()
+	user.ID = 1
+	feed := user.Feed()
+	if feed.Next() {
+		t.Fatal("Expected request to fail")
+	}
+	if transport.calls != 1 {
+		t.Fatalf("Expected 1 call, got %d", transport.calls)
+	}
+}
+
+func TestRetryPost(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tests := []struct {
+		name      string
+		transport *flakyTransport
+		calls     int32
+	}{
+		{"server error", &flakyTransport{n: 1, status: 500}, 1},
+		{"connection reset", &flakyTransport{n: 1}, 1},
+		{"rate limited", &flakyTransport{n: 1, status: 429}, 2},
+		{"dial error", &flakyTransport{n: 1, err: dialErr}, 2},
+	}
+
+	for _, test := range tests {
+		insta := newRetryInsta(t, test.transport)
+		insta.Account = &goinsta.Account{ID: 2}
+		user := insta.NewUser()
+		user.ID = 1
+		err := user.Follow()
+		if test.calls > 1 && err != nil {
+			t.Fatalf("%s: expected follow to succeed after retry, got: %v", test.name, err)
+		}
+		if test.calls == 1 && err == nil {
+			t.Fatalf("%s: expected follow to fail without retrying", test.name)
+		}
+		if test.transport.calls != test.calls {
+			t.Fatalf("%s: expected %d calls, got %d", test.name, test.calls, test.transport.calls)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := goinsta.DefaultRetryPolicy()
+	policy.Jitter = 0
+
+	if d := policy.Delay(1, goinsta.ErrorN{Status: "500"}, nil); d != policy.BaseDelay {
+		t.Fatalf("Expected first delay to be %s, got %s", policy.BaseDelay, d)
+	}
+	if d := policy.Delay(3, goinsta.ErrorN{Status: "500"}, nil); d != 4*policy.BaseDelay {
+		t.Fatalf("Expected third delay to be %s, got %s", 4*policy.BaseDelay, d)
+	}
+	if d := policy.Delay(1, goinsta.ErrTooManyRequests, nil); d != goinsta.TooManyRequestsTimeout {
+		t.Fatalf("Expected rate limit delay to be %s, got %s", goinsta.TooManyRequestsTimeout, d)
+	}
+	if d := policy.Delay(20, goinsta.ErrTooManyRequests, nil); d != policy.MaxDelay {
+		t.Fatalf("Expected delay to be capped at %s, got %s", policy.MaxDelay, d)
+	}
+
+	h := http.Header{"Retry-After": []string{"7"}}
+	if d := policy.Delay(1, goinsta.ErrTooManyRequests, h); d != 7*time.Second {
+		t.Fatalf("Expected Retry-After to be honored, got %s", d)
+	}
+
+	h = http.Header{"Retry-After": []string{"86400"}}
+	if d := policy.Delay(1, goinsta.ErrTooManyRequests, h); d != policy.MaxDelay {
+		t.Fatalf("Expected Retry-After to be capped at %s, got %s", policy.MaxDelay, d)
+	}
+	hint := goinsta.RateLimitError{APIError: goinsta.APIError{Retryable: true, RetryAfter: time.Hour}}
+	if d := policy.Delay(1, hint, nil); d != policy.MaxDelay {
+		t.Fatalf("Expected retry hint to be capped at %s, got %s", policy.MaxDelay, d)
+	}
+}
@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/boltstore"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func testSessionStore(t *testing.T, store goinsta.SessionStore) {
+	if _, err := store.Load("alice"); !errors.Is(err, goinsta.ErrSessionNotFound) {
+		t.Fatalf("Expected ErrSessionNotFound, got: %v", err)
+	}
+
+	for _, user := range []string{"bob", "alice"} {
+		config := goinsta.ConfigFile{ID: 1, User: user, Token: "token-" + user}
+		if err := store.Save(config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.User != "alice" || config.Token != "token-alice" {
+		t.Fatalf("Loaded wrong session: %+v", config)
+	}
+
+	users, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0] != "alice" || users[1] != "bob" {
+		t.Fatalf("Unexpected sessions %v", users)
+	}
+
+	if err := store.Delete("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Load("bob"); !errors.Is(err, goinsta.ErrSessionNotFound) {
+		t.Fatalf("Expected deleted session to be gone, got: %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := goinsta.NewFileStore(filepath.Join(t.TempDir(), "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSessionStore(t, store)
+
+	if err := store.Save(goinsta.ConfigFile{User: "../alice"}); err == nil {
+		t.Fatal("Expected username with a path separator to be rejected")
+	}
+}
+
+func TestBoltStore(t *testing.T) {
+	store, err := boltstore.Open(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	testSessionStore(t, store)
+}
+
+// headerTransport answers every request with a successful response, with the
+// headers provided.
+type headerTransport struct {
+	header http.Header
+}
+
+func (h *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	resp, err := okTransport{}.RoundTrip(r)
+	resp.Header = h.header.Clone()
+	return resp, err
+}
+
+func TestSessionSaveOnChange(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+
+	store, err := goinsta.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insta := goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.SetSessionStore(store); err != nil {
+		t.Fatal(err)
+	}
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Logging in should have saved the session
+	config, err := store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Account == nil || config.Account.ID != insta.Account.ID {
+		t.Fatal("Expected logged in session to be saved")
+	}
+	auth := config.HeaderOptions["Authorization"]
+	if auth == "" {
+		t.Fatal("Expected authorization header to be saved")
+	}
+
+	// A refreshed header should be saved in the background, after the delay
+	insta.SetSessionSaveDelay(50 * time.Millisecond)
+	transport := &headerTransport{header: http.Header{"Ig-Set-X-Mid": []string{"refreshed-mid"}}}
+	insta.SetHTTPTransport(transport)
+	user := insta.NewUser()
+	user.ID = 1
+	user.Feed().Next()
+
+	config, err = store.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HeaderOptions["X-Mid"] == "refreshed-mid" {
+		t.Fatal("Expected refreshed header not to be saved right away")
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		time.Sleep(10 * time.Millisecond)
+		if config, err = store.Load("alice"); err != nil {
+			t.Fatal(err)
+		}
+		if config.HeaderOptions["X-Mid"] == "refreshed-mid" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected refreshed header to be saved, got %v", config.HeaderOptions)
+		}
+	}
+	if config.HeaderOptions["Authorization"] != auth {
+		t.Fatalf("Expected authorization header to be kept, got %v", config.HeaderOptions)
+	}
+
+	// The saved session can be loaded again
+	loaded, err := goinsta.LoadSession(store, "alice", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Account.ID != insta.Account.ID {
+		t.Fatalf("Loaded wrong account %d", loaded.Account.ID)
+	}
+}
+
+// countingStore counts the sessions saved.
+type countingStore struct {
+	goinsta.SessionStore
+	mu    sync.Mutex
+	saves int
+}
+
+func (s *countingStore) Save(config goinsta.ConfigFile) error {
+	s.mu.Lock()
+	s.saves++
+	s.mu.Unlock()
+	return s.SessionStore.Save(config)
+}
+
+func (s *countingStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.saves
+}
+
+func TestSessionSaveBatched(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+
+	files, err := goinsta.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := &countingStore{SessionStore: files}
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	if err := insta.SetSessionStore(store); err != nil {
+		t.Fatal(err)
+	}
+	saved := store.count()
+
+	// Headers refreshed on every response are not saved on every request
+	transport := &headerTransport{}
+	insta.SetHTTPTransport(transport)
+	for i := 0; i < 10; i++ {
+		transport.header = http.Header{"Ig-Set-Ig-U-Rur": []string{fmt.Sprintf("rur-%d", i)}}
+		user := insta.NewUser()
+		user.ID = 1
+		user.Feed().Next()
+	}
+	if n := store.count() - saved; n != 0 {
+		t.Fatalf("Expected no saves while requests are made, got %d", n)
+	}
+
+	// Saving right away includes the pending changes
+	if err := insta.SaveSession(); err != nil {
+		t.Fatal(err)
+	}
+	config, err := files.Load("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.HeaderOptions["Ig-U-Rur"] != "rur-9" {
+		t.Fatalf("Expected last refreshed header to be saved, got %v", config.HeaderOptions)
+	}
+	if n := store.count() - saved; n != 1 {
+		t.Fatalf("Expected to save once, got %d", n)
+	}
+}
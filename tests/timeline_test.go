@@ -1,7 +1,6 @@
 package tests
 
 import (
-	"math/rand"
 	"path"
 	"strconv"
 	"testing"
@@ -11,7 +10,7 @@ import (
 )
 
 func TestTimeline(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -28,14 +27,14 @@ outside:
 		if i == 5 {
 			break outside
 		}
-		time.Sleep(time.Duration(rand.Intn(10)) * time.Second)
+		pause(10)
 	}
 
 	t.Logf("Gathered %d posts, %f on last request\n", len(tl.Items), tl.NumResults)
 }
 
 func TestDownload(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -49,8 +48,7 @@ func TestDownload(t *testing.T) {
 		t.Fatal("No posts found")
 	}
 
-	rand.Seed(time.Now().UnixNano())
-	randN := rand.Intn(len(posts))
+	randN := testRand.Intn(len(posts))
 	post := posts[randN]
 
 	folder := "downloads/" + strconv.FormatInt(time.Now().Unix(), 10)
@@ -59,7 +57,7 @@ func TestDownload(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	randN = rand.Intn(len(posts))
+	randN = testRand.Intn(len(posts))
 	post = posts[randN]
 	err = post.DownloadTo(path.Join(folder, "testy"))
 	if err != nil {
@@ -72,3 +70,28 @@ func TestDownload(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestTimelineTrayError(t *testing.T) {
+	insta := goinsta.New("goinsta_test", "password")
+	insta.SetHTTPTransport(&statusTransport{status: 404, body: `{"message":"not found","status":"fail"}`})
+	insta.SetWarnHandler(func(...interface{}) {})
+	insta.SetWrapper(goinsta.NewWrapper(&goinsta.RetryPolicy{MaxAttempts: 1}))
+
+	// Both the timeline and the tray fail, the error of the tray must not
+	// block Next from returning.
+	done := make(chan bool)
+	go func() {
+		done <- insta.Timeline.Next()
+	}()
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("Expected request to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeline.Next did not return after the tray failed")
+	}
+	if insta.Timeline.Error() == nil {
+		t.Fatal("Expected an error")
+	}
+}
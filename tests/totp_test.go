@@ -0,0 +1,193 @@
+package tests
+
+import (
+	"encoding/base32"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3/utilities"
+)
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 4226, appendix D
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	expected := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+	for counter, want := range expected {
+		code, err := utilities.GenerateHOTP(secret, uint64(counter), utilities.OTPOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != want {
+			t.Errorf("Counter %d: expected %s, got %s", counter, want, code)
+		}
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238, appendix B
+	secrets := map[utilities.OTPAlgorithm]string{
+		utilities.AlgorithmSHA1:   "12345678901234567890",
+		utilities.AlgorithmSHA256: "12345678901234567890123456789012",
+		utilities.AlgorithmSHA512: "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+	vectors := []struct {
+		time int64
+		alg  utilities.OTPAlgorithm
+		code string
+	}{
+		{59, utilities.AlgorithmSHA1, "94287082"},
+		{59, utilities.AlgorithmSHA256, "46119246"},
+		{59, utilities.AlgorithmSHA512, "90693936"},
+		{1111111109, utilities.AlgorithmSHA1, "07081804"},
+		{1111111109, utilities.AlgorithmSHA256, "68084774"},
+		{1111111109, utilities.AlgorithmSHA512, "25091201"},
+		{1111111111, utilities.AlgorithmSHA1, "14050471"},
+		{1111111111, utilities.AlgorithmSHA256, "67062674"},
+		{1111111111, utilities.AlgorithmSHA512, "99943326"},
+		{1234567890, utilities.AlgorithmSHA1, "89005924"},
+		{1234567890, utilities.AlgorithmSHA256, "91819424"},
+		{1234567890, utilities.AlgorithmSHA512, "93441116"},
+		{2000000000, utilities.AlgorithmSHA1, "69279037"},
+		{2000000000, utilities.AlgorithmSHA256, "90698825"},
+		{2000000000, utilities.AlgorithmSHA512, "38618901"},
+		{20000000000, utilities.AlgorithmSHA1, "65353130"},
+		{20000000000, utilities.AlgorithmSHA256, "77737706"},
+		{20000000000, utilities.AlgorithmSHA512, "47863826"},
+	}
+	for _, v := range vectors {
+		secret := base32.StdEncoding.EncodeToString([]byte(secrets[v.alg]))
+		opts := utilities.OTPOptions{Digits: 8, Algorithm: v.alg}
+		code, err := utilities.GenerateTOTP(secret, time.Unix(v.time, 0), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("%s at %d: expected %s, got %s", v.alg, v.time, v.code, code)
+		}
+	}
+}
+
+func TestTOTPSkew(t *testing.T) {
+	secret := "JBSWY3DPEHPK3PXP"
+	now := time.Unix(1700000000, 0)
+	previous, _ := utilities.GenerateTOTP(secret, now.Add(-30*time.Second), utilities.OTPOptions{})
+	next, _ := utilities.GenerateTOTP(secret, now.Add(30*time.Second), utilities.OTPOptions{})
+	later, _ := utilities.GenerateTOTP(secret, now.Add(60*time.Second), utilities.OTPOptions{})
+
+	for code, want := range map[string]bool{previous: true, next: true, later: false} {
+		ok, err := utilities.ValidateTOTP(secret, code, now, utilities.OTPOptions{Skew: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != want {
+			t.Errorf("Expected validity of %s to be %v", code, want)
+		}
+	}
+	if ok, _ := utilities.ValidateTOTP(secret, next, now, utilities.OTPOptions{}); ok {
+		t.Error("Expected the next code to be invalid without skew")
+	}
+
+	// The current code comes first
+	codes, err := utilities.TOTPWindow(secret, now, utilities.OTPOptions{Skew: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, _ := utilities.GenerateTOTP(secret, now, utilities.OTPOptions{})
+	if len(codes) != 3 || codes[0] != current {
+		t.Fatalf("Expected the current code first, got %v", codes)
+	}
+
+	// Secrets as shown by authenticator apps are accepted
+	code, err := utilities.GenerateTOTP("jbsw y3dp ehpk 3pxp", now, utilities.OTPOptions{})
+	if err != nil || code != current {
+		t.Fatalf("Expected the secret to be normalized, got %s: %v", code, err)
+	}
+}
+
+func TestOTPAuthURI(t *testing.T) {
+	key, err := utilities.ParseOTPAuthURI(
+		"otpauth://totp/ACME%20Co:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=ACME%20Co&algorithm=SHA256&digits=8&period=60",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Type != "totp" || key.Issuer != "ACME Co" || key.Account != "alice@example.com" ||
+		key.Secret != "JBSWY3DPEHPK3PXP" || key.Algorithm != utilities.AlgorithmSHA256 ||
+		key.Digits != 8 || key.Period != 60 {
+		t.Fatalf("Unexpected key: %+v", key)
+	}
+
+	// The URI round trips
+	parsed, err := utilities.ParseOTPAuthURI(key.URI())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *parsed != *key {
+		t.Fatalf("Expected %+v, got %+v", key, parsed)
+	}
+
+	// Defaults are omitted
+	uri := utilities.NewTOTPKey("Instagram", "alice", "JBSWY3DPEHPK3PXP").URI()
+	if uri != "otpauth://totp/Instagram:alice?issuer=Instagram&secret=JBSWY3DPEHPK3PXP" {
+		t.Fatalf("Unexpected URI: %s", uri)
+	}
+
+	hotp, err := utilities.ParseOTPAuthURI("otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := utilities.GenerateHOTP(hotp.Secret, 5, utilities.OTPOptions{})
+	if ok, _ := hotp.Validate(code, time.Time{}); !ok {
+		t.Fatal("Expected the code of the initial counter to be valid")
+	}
+
+	invalid := []string{
+		"https://totp/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://motp/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/alice",
+		"otpauth://totp/alice?secret=not-base32",
+		"otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=six",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=-6",
+		"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=-30",
+	}
+	for _, uri := range invalid {
+		if _, err := utilities.ParseOTPAuthURI(uri); !errors.Is(err, utilities.ErrOTPURI) {
+			t.Errorf("Expected ErrOTPURI for %s, got %v", uri, err)
+		}
+	}
+	if _, err := utilities.ParseOTPAuthURI("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&algorithm=MD5"); !errors.Is(err, utilities.ErrOTPAlgorithm) {
+		t.Errorf("Expected ErrOTPAlgorithm, got %v", err)
+	}
+}
+
+func TestOTPOptionsInvalid(t *testing.T) {
+	secret := "JBSWY3DPEHPK3PXP"
+	now := time.Now()
+	invalid := []struct {
+		opts utilities.OTPOptions
+		err  error
+	}{
+		{utilities.OTPOptions{Digits: -1}, utilities.ErrOTPDigits},
+		{utilities.OTPOptions{Digits: 11}, utilities.ErrOTPDigits},
+		{utilities.OTPOptions{Period: -30}, utilities.ErrOTPPeriod},
+		{utilities.OTPOptions{Skew: -1}, utilities.ErrOTPSkew},
+		{utilities.OTPOptions{Skew: utilities.MaxOTPSkew + 1}, utilities.ErrOTPSkew},
+		{utilities.OTPOptions{Algorithm: "MD5"}, utilities.ErrOTPAlgorithm},
+	}
+	for _, v := range invalid {
+		if _, err := utilities.GenerateTOTP(secret, now, v.opts); !errors.Is(err, v.err) {
+			t.Errorf("GenerateTOTP %+v: expected %v, got %v", v.opts, v.err, err)
+		}
+		if _, err := utilities.TOTPWindow(secret, now, v.opts); !errors.Is(err, v.err) {
+			t.Errorf("TOTPWindow %+v: expected %v, got %v", v.opts, v.err, err)
+		}
+		if _, err := utilities.ValidateTOTP(secret, "123456", now, v.opts); !errors.Is(err, v.err) {
+			t.Errorf("ValidateTOTP %+v: expected %v, got %v", v.opts, v.err, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Davincible/goinsta/v3"
+	"github.com/Davincible/goinsta/v3/goinstatest"
+)
+
+func TestEnableTOTP(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	backup, err := insta.Account.EnableTOTP(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup) == 0 {
+		t.Fatal("Expected backup codes")
+	}
+
+	// The seed is stored, and exported
+	seed := srv.TOTPSeed(alice.ID)
+	config := insta.ExportConfig()
+	if seed == "" || config.TOTP == nil || config.TOTP.Seed != seed {
+		t.Fatalf("Expected the seed to be exported, got %+v", config.TOTP)
+	}
+
+	info, err := insta.Account.SecurityInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.TwoFactorEnabled || !info.TOTPTwoFactorEnabled {
+		t.Fatalf("Expected TOTP two factor to be enabled, got %+v", info)
+	}
+
+	// Logging in again generates the code from the seed
+	insta = goinsta.New("alice", "secret", seed)
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+	devices, err := insta.Account.TrustedDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 1 {
+		t.Fatalf("Expected the device to be trusted, got %+v", devices)
+	}
+
+	if err := insta.Account.DisableTOTP(); err != nil {
+		t.Fatal(err)
+	}
+	if srv.TOTPSeed(alice.ID) != "" || insta.ExportConfig().TOTP != nil {
+		t.Fatal("Expected the seed to be forgotten")
+	}
+	fakeLogin(t, srv, "alice", "secret")
+}
+
+func TestBackupCodes(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	if _, err := insta.Account.EnableTOTP(nil); err != nil {
+		t.Fatal(err)
+	}
+	old, err := insta.Account.BackupCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := insta.Account.RegenerateBackupCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != len(old) || codes[0] == old[0] {
+		t.Fatalf("Expected new backup codes, got %v, previously %v", codes, old)
+	}
+
+	// Backup codes can be used instead of a TOTP code
+	insta = goinsta.New("alice", "secret")
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); err == nil {
+		t.Fatal("Expected two factor login to be required")
+	}
+	if err := insta.TwoFactorInfo.Login2FA(codes[0]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTOTPClockDrift(t *testing.T) {
+	srv := goinstatest.NewServer()
+	defer srv.Close()
+	alice := srv.AddUser("alice", "secret")
+
+	insta := fakeLogin(t, srv, "alice", "secret")
+	if _, err := insta.Account.EnableTOTP(nil); err != nil {
+		t.Fatal(err)
+	}
+	key := insta.TOTPKey()
+	if key == nil || key.Secret != srv.TOTPSeed(alice.ID) || key.Account != "alice" {
+		t.Fatalf("Unexpected TOTP key: %+v", key)
+	}
+
+	// The server accepts the codes of one period before and after its time,
+	// the codes of the previous and next period are tried as well
+	srv.SetClockDrift(time.Minute)
+	insta = goinsta.New("alice", "secret", key.Secret)
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Beyond that login fails
+	srv.SetClockDrift(2 * time.Minute)
+	insta = goinsta.New("alice", "secret", key.Secret)
+	insta.SetWarnHandler(t.Log)
+	srv.Connect(insta)
+	if err := insta.Login(); !errors.Is(err, goinsta.ErrInvalidCode) {
+		t.Fatalf("Expected ErrInvalidCode, got: %v", err)
+	}
+}
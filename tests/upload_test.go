@@ -12,7 +12,7 @@ import (
 )
 
 func TestUploadPhoto(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -58,7 +58,7 @@ func TestUploadPhoto(t *testing.T) {
 }
 
 func TestUploadThumbVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -114,7 +114,7 @@ func TestUploadThumbVideo(t *testing.T) {
 }
 
 func TestUploadVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -163,7 +163,7 @@ func TestUploadVideo(t *testing.T) {
 }
 
 func TestUploadStoryPhoto(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -189,7 +189,7 @@ func TestUploadStoryPhoto(t *testing.T) {
 }
 
 func TestUploadStoryVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -217,7 +217,7 @@ func TestUploadStoryVideo(t *testing.T) {
 }
 
 func TestUploadStoryMultiVideo(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -249,7 +249,7 @@ func TestUploadStoryMultiVideo(t *testing.T) {
 }
 
 func TestUploadCarousel(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -310,7 +310,7 @@ func TestUploadCarousel(t *testing.T) {
 }
 
 func TestUploadProfilePicture(t *testing.T) {
-	insta, err := goinsta.EnvRandAcc()
+	insta, err := testAccount(t)
 	if err != nil {
 		t.Fatal(err)
 	}
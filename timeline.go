@@ -140,7 +140,7 @@ func (tl *Timeline) Next(p ...interface{}) bool {
 
 	wg := &sync.WaitGroup{}
 	defer wg.Wait()
-	errChan := make(chan error)
+	errChan := make(chan error, 1)
 
 	if reason != PAGINATION {
 		tl.sessionID = generateUUID()
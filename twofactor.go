@@ -1,8 +1,11 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Davincible/goinsta/v3/utilities"
 )
@@ -37,22 +40,29 @@ type phoneVerificationSettings struct {
 
 // Login2FA allows for a login through 2FA
 // You can either provide a code directly by passing it as a parameter, or
-//  goinsta can generate one for you as long as the TOTP seed is set.
+// goinsta can generate one for you as long as the TOTP seed is set. If
+// Instagram rejects the generated code, the codes of the previous and next
+// periods are tried, in case the clock is off. Without a seed, the code is
+// requested from the code provider, see SetCodeProvider.
 func (info *TwoFactorInfo) Login2FA(in ...string) error {
 	insta := info.insta
 
-	var code string
+	var err error
 	if len(in) > 0 {
-		code = in[0]
-	} else if info.insta.totp == nil || info.insta.totp.Seed == "" {
-		return Err2FANoCode
+		_, err = info.login2FA(in[0])
 	} else {
-		otp, err := utilities.GenTOTP(insta.totp.Seed)
-		if err != nil {
-			return fmt.Errorf("Failed to generate 2FA OTP code: %w", err)
-		}
-		code = otp
+		_, err = info.resolve(insta.Context())
+	}
+	if err != nil {
+		return err
 	}
+	return insta.OpenApp()
+}
+
+// login2FA logs in with a 2FA code, without running the post-login sequence,
+// and returns the login response.
+func (info *TwoFactorInfo) login2FA(code string) ([]byte, error) {
+	insta := info.insta
 
 	data, err := json.Marshal(
 		map[string]string{
@@ -64,11 +74,11 @@ func (info *TwoFactorInfo) Login2FA(in ...string) error {
 			"guid":                  insta.uuid,
 			"device_id":             insta.dID,
 			"waterfall_id":          generateUUID(),
-			"verification_method":   "3",
+			"verification_method":   info.verificationMethod(),
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	body, _, err := insta.sendRequest(
 		&reqOptions{
@@ -88,16 +98,95 @@ func (info *TwoFactorInfo) Login2FA(in ...string) error {
 		},
 	)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = insta.parseLogin(body)
+	if err := insta.parseLogin(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// codeMethod returns how the 2FA code is delivered.
+func (info *TwoFactorInfo) codeMethod() CodeMethod {
+	switch {
+	case info.TotpTwoFactorOn:
+		return CodeTOTP
+	case info.WhatsappTwoFactorOn:
+		return CodeWhatsApp
+	default:
+		return CodeSMS
+	}
+}
+
+// verificationMethod returns the verification method to login with, as
+// expected by Instagram.
+func (info *TwoFactorInfo) verificationMethod() string {
+	switch info.codeMethod() {
+	case CodeWhatsApp:
+		return "6"
+	case CodeSMS:
+		return "1"
+	default:
+		return "3"
+	}
+}
+
+// resolve logs in with codes generated from the TOTP seed if set, or else
+// with a code requested from the code provider, see SetCodeProvider. It
+// returns the login response.
+func (info *TwoFactorInfo) resolve(ctx context.Context) ([]byte, error) {
+	insta := info.insta
+	if insta.totp != nil && insta.totp.Seed != "" {
+		return info.loginTOTP()
+	}
+
+	code, err := info.requestCode(ctx)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	return info.login2FA(code)
+}
+
+// totpSkew is the number of periods before and after the current one, whose
+// codes are tried if Instagram rejects the current code, to tolerate a
+// drifting clock.
+const totpSkew = 1
+
+// loginTOTP logs in with a code generated from the TOTP seed. If Instagram
+// rejects the code, the codes of the neighbouring periods are tried.
+func (info *TwoFactorInfo) loginTOTP() ([]byte, error) {
+	insta := info.insta
+	codes, err := utilities.TOTPWindow(insta.totp.Seed, time.Now(), utilities.OTPOptions{Skew: totpSkew})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate 2FA OTP code: %w", err)
 	}
 
-	err = insta.OpenApp()
-	return err
+	for i, code := range codes {
+		var body []byte
+		body, err = info.login2FA(code)
+		if !errors.Is(err, ErrInvalidCode) {
+			return body, err
+		}
+		if i < len(codes)-1 {
+			insta.log().Warn("TOTP code has been rejected, the clock might be off, trying the next closest code")
+		}
+	}
+	return nil, err
+}
+
+// requestCode requests the code to login with from the code provider.
+func (info *TwoFactorInfo) requestCode(ctx context.Context) (string, error) {
+	insta := info.insta
+	req := CodeRequest{
+		Username: insta.user,
+		Reason:   CodeForTwoFactor,
+		Method:   info.codeMethod(),
+	}
+	if req.Method != CodeTOTP {
+		req.Destination = info.ObfuscatedPhoneNr
+	}
+	return insta.requestCode(ctx, req, Err2FANoCode)
 }
 
 // Check2FATrusted checks whether the device has been trusted.
@@ -136,3 +225,162 @@ func (info *TwoFactorInfo) Check2FATrusted() error {
 	err = info.Login2FA("")
 	return err
 }
+
+// SecurityInfo is the two factor authentication state of an account, see
+// Account.SecurityInfo.
+type SecurityInfo struct {
+	PhoneConfirmed              bool            `json:"is_phone_confirmed"`
+	TwoFactorEnabled            bool            `json:"is_two_factor_enabled"`
+	TOTPTwoFactorEnabled        bool            `json:"is_totp_two_factor_enabled"`
+	TrustedNotificationsEnabled bool            `json:"is_trusted_notifications_enabled"`
+	CountryCode                 int             `json:"country_code"`
+	NationalNumber              int64           `json:"national_number"`
+	BackupCodes                 []string        `json:"backup_codes"`
+	TrustedDevices              []TrustedDevice `json:"trusted_devices"`
+	Status                      string          `json:"status"`
+}
+
+// TrustedDevice is a device that doesn't need a two factor code to login.
+type TrustedDevice struct {
+	DeviceID      string  `json:"device_id"`
+	DeviceGUID    string  `json:"device_guid"`
+	DeviceName    string  `json:"device_name"`
+	LastLoginTime int64   `json:"last_login_time"`
+	Latitude      float64 `json:"latitude"`
+	Longitude     float64 `json:"longitude"`
+}
+
+// SecurityInfo fetches the two factor authentication state of the account,
+// including the backup codes and trusted devices.
+func (account *Account) SecurityInfo() (*SecurityInfo, error) {
+	info := &SecurityInfo{}
+	if err := account.send2FA(urlSecurityInfo, nil, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GenerateTOTPSeed generates a new TOTP seed, to enable TOTP two factor
+// authentication with, see EnableTOTP. The seed can be added to an
+// authenticator app, to generate codes there as well.
+func (account *Account) GenerateTOTPSeed() (*TOTP, error) {
+	seed := &TOTP{}
+	if err := account.send2FA(url2FAGenerateTOTP, nil, seed); err != nil {
+		return nil, err
+	}
+	if seed.Seed == "" {
+		return nil, errors.New("no TOTP seed has been generated")
+	}
+	return seed, nil
+}
+
+// EnableTOTP enables TOTP two factor authentication with seed, and returns the
+// backup codes. If seed is nil, a new seed is generated with GenerateTOTPSeed.
+//
+// The seed is stored, so codes are generated when logging in, and included in
+// the exported config. Make sure to keep the backup codes, as they are needed
+// to login if the seed is lost.
+func (account *Account) EnableTOTP(seed *TOTP) ([]string, error) {
+	insta := account.insta
+
+	if seed == nil {
+		var err error
+		if seed, err = account.GenerateTOTPSeed(); err != nil {
+			return nil, err
+		}
+	}
+	code, err := utilities.GenTOTP(seed.Seed)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate 2FA OTP code: %w", err)
+	}
+
+	resp := struct {
+		BackupCodes []string `json:"backup_codes"`
+	}{}
+	err = account.send2FA(url2FAEnableTOTP, map[string]string{
+		"verification_code": code,
+		"totp_seed_id":      toString(seed.ID),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	insta.totp = seed
+	insta.sessionChanged()
+	return resp.BackupCodes, nil
+}
+
+// DisableTOTP disables TOTP two factor authentication, and forgets the seed.
+func (account *Account) DisableTOTP() error {
+	insta := account.insta
+	if err := account.send2FA(url2FADisableTOTP, nil, nil); err != nil {
+		return err
+	}
+
+	insta.totp = nil
+	insta.sessionChanged()
+	return nil
+}
+
+// BackupCodes fetches the backup codes, that can be used to login instead of
+// a two factor code.
+func (account *Account) BackupCodes() ([]string, error) {
+	info, err := account.SecurityInfo()
+	if err != nil {
+		return nil, err
+	}
+	return info.BackupCodes, nil
+}
+
+// RegenerateBackupCodes replaces the backup codes with new ones, and returns
+// them.
+func (account *Account) RegenerateBackupCodes() ([]string, error) {
+	resp := struct {
+		BackupCodes []string `json:"backup_codes"`
+	}{}
+	if err := account.send2FA(url2FABackupCodes, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.BackupCodes, nil
+}
+
+// TrustedDevices fetches the devices that don't need a two factor code to
+// login.
+func (account *Account) TrustedDevices() ([]TrustedDevice, error) {
+	info, err := account.SecurityInfo()
+	if err != nil {
+		return nil, err
+	}
+	return info.TrustedDevices, nil
+}
+
+// send2FA posts a two factor settings request, and decodes the response into
+// resp, if not nil.
+func (account *Account) send2FA(endpoint string, form map[string]string, resp interface{}) error {
+	insta := account.insta
+
+	query := map[string]string{
+		"_uid":      toString(account.ID),
+		"_uuid":     insta.uuid,
+		"device_id": insta.dID,
+	}
+	for k, v := range form {
+		query[k] = v
+	}
+	data, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+
+	body, _, err := insta.sendRequest(
+		&reqOptions{
+			Endpoint: endpoint,
+			IsPost:   true,
+			Query:    generateSignature(data),
+		},
+	)
+	if err != nil || resp == nil {
+		return err
+	}
+	return json.Unmarshal(body, resp)
+}
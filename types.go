@@ -3,10 +3,14 @@ package goinsta
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // ConfigFile is a structure to store the session information so that can be exported or imported.
 type ConfigFile struct {
+	// Schema version, see ConfigVersion
+	Version int `json:"version"`
+
 	ID            int64             `json:"id"`
 	User          string            `json:"username"`
 	DeviceID      string            `json:"device_id"`
@@ -21,6 +25,10 @@ type ConfigFile struct {
 	Device        Device            `json:"device"`
 	TOTP          *TOTP             `json:"totp"`
 	SessionNonce  string            `json:"session"`
+	BaseURLs      *BaseURLs         `json:"base_urls,omitempty"`
+
+	// Actions blocked by Instagram, with the time the block expires
+	Cooldowns map[Action]time.Time `json:"cooldowns,omitempty"`
 }
 
 type Device struct {
@@ -50,6 +58,9 @@ type ErrorN struct {
 	Endpoint  string `json:"endpoint"`
 	Status    string `json:"status"`
 	ErrorType string `json:"error_type"`
+
+	// HTTP status code
+	Code int `json:"-"`
 }
 
 // Error503 is instagram API error
@@ -139,8 +150,15 @@ func (e *Error400) GetMessage() string {
 	return ""
 }
 
-// ChallengeError is error returned by HTTP 400 status code.
+// ChallengeError is returned if a challenge or checkpoint has to be completed
+// before requests can be made. It wraps ErrChallengeRequired, or
+// ErrCheckpointRequired.
+//
+// Message and ErrorType hold the same values as in the embedded APIError,
+// they are kept for compatibility.
 type ChallengeError struct {
+	APIError
+
 	Challenge struct {
 		URL               string `json:"url"`
 		APIPath           string `json:"api_path"`
@@ -155,7 +173,11 @@ type ChallengeError struct {
 }
 
 func (e ChallengeError) Error() string {
-	return fmt.Sprintf("%s: %s, %s", ErrChallengeRequired.Error(), e.Status, e.Message)
+	err := e.Err
+	if err == nil {
+		err = ErrChallengeRequired
+	}
+	return fmt.Sprintf("%s: %s, %s", err.Error(), e.Status, e.Message)
 }
 
 // Nametag is part of the account information.
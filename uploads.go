@@ -2,6 +2,7 @@ package goinsta
 
 import (
 	"bytes"
+	"context"
 	cryptRand "crypto/rand"
 	"encoding/json"
 	"fmt"
@@ -45,6 +46,7 @@ type UploadOptions struct {
 	locationJSON string
 
 	// Internal config
+	ctx            context.Context
 	config         map[string]interface{}
 	configURL      string
 	uploadID       string
@@ -105,7 +107,14 @@ type LocationTag struct {
 // You can specify the options of your upload with the single parameter &UploadOptions{}
 // See the UploadOptions struct for more details.
 func (insta *Instagram) Upload(o *UploadOptions) (*Item, error) {
+	return insta.UploadContext(insta.Context(), o)
+}
+
+// UploadContext is like Upload, but the upload will be aborted when the
+// context is cancelled.
+func (insta *Instagram) UploadContext(ctx context.Context, o *UploadOptions) (*Item, error) {
 	o.insta = insta
+	o.ctx = ctx
 	o.startTime = toString(time.Now().Unix())
 
 	// Format User & Location Tags
@@ -142,7 +151,7 @@ func (insta *Instagram) Upload(o *UploadOptions) (*Item, error) {
 		}
 		return o.configureVideo()
 	default:
-		insta.infoHandler(fmt.Errorf("unable to handle file upload with format %s", t))
+		insta.log().Info("Unable to handle file upload", "format", t)
 		return nil, ErrInvalidFormat
 	}
 }
@@ -362,8 +371,10 @@ func (o *UploadOptions) postVideo() error {
 	// Upload video bytes
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:   o.ctx,
 			Endpoint:  fmt.Sprintf(urlUploadVideo, o.name),
 			OmitAPI:   true,
+			Upload:    true,
 			IsPost:    true,
 			DataBytes: o.buf,
 			ExtraHeaders: map[string]string{
@@ -414,8 +425,10 @@ func (o *UploadOptions) postVideoGET() error {
 	}
 	_, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:      o.ctx,
 			Endpoint:     fmt.Sprintf(urlUploadVideo, o.name),
 			OmitAPI:      true,
+			Upload:       true,
 			ExtraHeaders: headers,
 		},
 	)
@@ -433,8 +446,10 @@ func (o *UploadOptions) postPhoto() error {
 	// Upload Photo
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:   o.ctx,
 			Endpoint:  fmt.Sprintf(urlUploadPhoto, o.name),
 			OmitAPI:   true,
+			Upload:    true,
 			IsPost:    true,
 			DataBytes: o.buf,
 			ExtraHeaders: map[string]string{
@@ -721,7 +736,7 @@ func (o *UploadOptions) uploadAlbum() (*Item, error) {
 				return nil, err
 			}
 		default:
-			insta.infoHandler(fmt.Errorf("unable to handle file upload with format %s", t))
+			insta.log().Info("Unable to handle file upload", "format", t)
 			return nil, ErrInvalidFormat
 		}
 
@@ -767,6 +782,7 @@ func (o *UploadOptions) configure() (*Item, error) {
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  o.ctx,
 			Endpoint: o.configURL,
 			IsPost:   true,
 			Query:    generateSignature(data),
@@ -792,11 +808,13 @@ func (o *UploadOptions) configure() (*Item, error) {
 	if res.Status != "ok" {
 		switch res.Message {
 		case "Transcode not finished yet.":
-			insta.infoHandler("Waiting for transcode to finish...")
-			time.Sleep(6 * time.Second)
+			insta.log().Info("Waiting for transcode to finish")
+			if err := sleepContext(o.ctx, 6*time.Second); err != nil {
+				return nil, err
+			}
 			return o.configure()
 		case "media_needs_reupload":
-			insta.infoHandler(fmt.Errorf("instagram asks for the video to be reuploaded, please wait"))
+			insta.log().Info("Instagram asks for the video to be reuploaded, please wait")
 			err := o.postVideo()
 			if err != nil {
 				return nil, err
@@ -857,11 +875,12 @@ func (o *UploadOptions) uploadMultiStory() (*Item, error) {
 		o.width, o.height, o.duration = width, height, duration
 
 		size := float64(len(o.buf.Bytes())) / 1000000.0
-		o.insta.infoHandler(
-			fmt.Sprintf(
-				"Uploading story video %d: duration: %ds, Size: %dx%d, %.2f Mb",
-				i+1, duration/1000, width, height, size,
-			),
+		o.insta.log().Info("Uploading story video",
+			"index", i+1,
+			"duration", time.Duration(duration)*time.Millisecond,
+			"width", width,
+			"height", height,
+			"size_mb", size,
 		)
 
 		o.newUploadID()
@@ -922,11 +941,11 @@ func (o *UploadOptions) uploadVideo() error {
 	}
 
 	size := float64(len(o.buf.Bytes())) / 1000000.0
-	o.insta.infoHandler(
-		fmt.Sprintf(
-			"Upload video: duration: %ds, Size: %dx%d, %.2f Mb",
-			duration/1000, width, height, size,
-		),
+	o.insta.log().Info("Uploading video",
+		"duration", time.Duration(duration)*time.Millisecond,
+		"width", width,
+		"height", height,
+		"size_mb", size,
 	)
 
 	if err := o.createRUploadParams(); err != nil {
@@ -936,7 +955,7 @@ func (o *UploadOptions) uploadVideo() error {
 	// Warn on large video size
 	t := 1 << 22
 	if o.buf.Len() > t*4 {
-		o.insta.warnHandler("Video size is fairy large, if you have trouble uploading, try a smaller video.")
+		o.insta.log().Warn("Video size is fairly large, if you have trouble uploading, try a smaller video")
 	}
 
 	rand := random(1000000000, 9999999999)
@@ -994,8 +1013,10 @@ func (o *UploadOptions) segmentTransfer(segment []byte) error {
 	// Upload video bytes
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:      o.ctx,
 			Endpoint:     fmt.Sprintf(urlUploadVideo, o.name),
 			OmitAPI:      true,
+			Upload:       true,
 			IsPost:       true,
 			DataBytes:    bytes.NewBuffer(segment),
 			ExtraHeaders: headers,
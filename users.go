@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"path"
@@ -48,6 +49,12 @@ func (users *Users) SetInstagram(insta *Instagram) {
 //
 // returns false when list reach the end.
 func (users *Users) Next() bool {
+	return users.NextContext(users.insta.Context())
+}
+
+// NextContext is like Next, but the request will be aborted when the context
+// is cancelled.
+func (users *Users) NextContext(ctx context.Context) bool {
 	if users.err != nil {
 		return false
 	}
@@ -70,6 +77,7 @@ func (users *Users) Next() bool {
 
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  ctx,
 			Endpoint: endpoint,
 			Query:    query,
 		},
@@ -576,6 +584,12 @@ func generateMuteData(user *User, opt muteOption) map[string]string {
 //
 // See example: examples/user/follow.go
 func (user *User) Follow() error {
+	return user.FollowContext(user.insta.Context())
+}
+
+// FollowContext is like Follow, but the request will be aborted when the
+// context is cancelled.
+func (user *User) FollowContext(ctx context.Context) error {
 	insta := user.insta
 	data, err := json.Marshal(
 		map[string]string{
@@ -591,9 +605,11 @@ func (user *User) Follow() error {
 	}
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  ctx,
 			Endpoint: fmt.Sprintf(urlUserFollow, user.ID),
 			Query:    generateSignature(data),
 			IsPost:   true,
+			Action:   ActionFollow,
 		},
 	)
 	if err != nil {
@@ -615,6 +631,12 @@ func (user *User) Follow() error {
 //
 // See example: examples/user/unfollow.go
 func (user *User) Unfollow() error {
+	return user.UnfollowContext(user.insta.Context())
+}
+
+// UnfollowContext is like Unfollow, but the request will be aborted when the
+// context is cancelled.
+func (user *User) UnfollowContext(ctx context.Context) error {
 	insta := user.insta
 	data, err := json.Marshal(
 		map[string]string{
@@ -631,9 +653,11 @@ func (user *User) Unfollow() error {
 	}
 	body, _, err := insta.sendRequest(
 		&reqOptions{
+			Context:  ctx,
 			Endpoint: fmt.Sprintf(urlUserUnfollow, user.ID),
 			Query:    generateSignature(data),
 			IsPost:   true,
+			Action:   ActionFollow,
 		},
 	)
 	if err != nil {
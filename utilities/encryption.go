@@ -55,6 +55,32 @@ func AESGCMEncrypt(key, data, additionalData []byte) (iv, encrypted, tag []byte,
 	return
 }
 
+// AESGCMDecrypt reverses AESGCMEncrypt.
+func AESGCMDecrypt(key, iv, encrypted, tag, additionalData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating cipher: %w", err)
+	}
+
+	aesgcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("error when creating gcm: %w", err)
+	}
+
+	// The nonce and tag come from untrusted input, Open panics on a nonce of
+	// the wrong size
+	if len(iv) != aesgcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d, expected %d", len(iv), aesgcm.NonceSize())
+	}
+	if len(tag) != aesgcm.Overhead() {
+		return nil, fmt.Errorf("invalid tag length %d, expected %d", len(tag), aesgcm.Overhead())
+	}
+
+	sealed := make([]byte, 0, len(encrypted)+len(tag))
+	sealed = append(append(sealed, encrypted...), tag...)
+	return aesgcm.Open(nil, iv, sealed, additionalData)
+}
+
 func RSAPublicKeyPKCS1Encrypt(publicKey *rsa.PublicKey, data []byte) ([]byte, error) {
 	return rsa.EncryptPKCS1v15(rand.Reader, publicKey, data)
 }
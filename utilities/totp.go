@@ -1,67 +1,373 @@
 package utilities
 
 import (
-	"bytes"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/base32"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// prefix will append extra 0s if the length of otp is less than 6.
-func prefix(otp string) string {
-	if len(otp) == 6 {
-		return otp
+// OTPAlgorithm is the HMAC hash algorithm used to generate one time passwords.
+type OTPAlgorithm string
+
+const (
+	AlgorithmSHA1   OTPAlgorithm = "SHA1"
+	AlgorithmSHA256 OTPAlgorithm = "SHA256"
+	AlgorithmSHA512 OTPAlgorithm = "SHA512"
+)
+
+// Defaults used by authenticator apps, and Instagram.
+const (
+	DefaultOTPDigits = 6
+	DefaultOTPPeriod = 30
+)
+
+// MaxOTPSkew is the largest number of periods accepted as skew.
+const MaxOTPSkew = 100
+
+var (
+	ErrOTPAlgorithm = errors.New("unsupported OTP algorithm")
+	ErrOTPDigits    = errors.New("OTP digits must be between 1 and 10")
+	ErrOTPPeriod    = errors.New("OTP period must be positive")
+	ErrOTPSkew      = errors.New("OTP skew must be between 0 and 100")
+	ErrOTPURI       = errors.New("invalid otpauth URI")
+)
+
+// OTPOptions configures how one time passwords are generated and validated.
+// The zero value uses the defaults: 6 digits, a 30 second period, SHA1, and
+// no clock skew. Negative values are rejected.
+type OTPOptions struct {
+	// Digits is the length of the codes
+	Digits int
+
+	// Period is the number of seconds a TOTP code is valid for
+	Period int
+
+	Algorithm OTPAlgorithm
+
+	// Skew is the number of periods before and after the current one, whose
+	// codes are accepted as well, to tolerate clock drift
+	Skew int
+}
+
+func (o OTPOptions) digits() int {
+	if o.Digits == 0 {
+		return DefaultOTPDigits
+	}
+	return o.Digits
+}
+
+func (o OTPOptions) period() int64 {
+	if o.Period == 0 {
+		return DefaultOTPPeriod
 	}
-	for i := (6 - len(otp)); i > 0; i-- {
-		otp = "0" + otp
+	return int64(o.Period)
+}
+
+// validate checks the options, before the digits are used as exponent, the
+// period as divisor, and the skew as window size.
+func (o OTPOptions) validate() error {
+	if d := o.digits(); d < 1 || d > 10 {
+		return ErrOTPDigits
+	}
+	if o.Period < 0 {
+		return ErrOTPPeriod
+	}
+	if o.Skew < 0 || o.Skew > MaxOTPSkew {
+		return ErrOTPSkew
+	}
+	_, err := o.hash()
+	return err
+}
+
+func (o OTPOptions) hash() (func() hash.Hash, error) {
+	switch OTPAlgorithm(strings.ToUpper(string(o.Algorithm))) {
+	case "", AlgorithmSHA1:
+		return sha1.New, nil
+	case AlgorithmSHA256:
+		return sha256.New, nil
+	case AlgorithmSHA512:
+		return sha512.New, nil
 	}
-	return otp
+	return nil, fmt.Errorf("%w: %s", ErrOTPAlgorithm, o.Algorithm)
 }
 
-// genHOTP will generate a Hmac One Time Password
-func genHOTP(secret string, interval int64) (string, error) {
-	// Decode base32 secret
-	key, err := base32.StdEncoding.DecodeString(strings.ToUpper(secret))
+// DecodeOTPSecret decodes a base32 encoded secret. Secrets are accepted in any
+// case, with or without padding, and with spaces, as authenticator apps show
+// them.
+func DecodeOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+}
+
+// GenerateHOTP generates the HMAC based one time password for counter, as
+// described in RFC 4226. The secret is base32 encoded.
+func GenerateHOTP(secret string, counter uint64, opts OTPOptions) (string, error) {
+	key, err := DecodeOTPSecret(secret)
 	if err != nil {
 		return "", err
 	}
+	return hotp(key, counter, opts)
+}
 
-	bs := make([]byte, 8)
-	binary.BigEndian.PutUint64(bs, uint64(interval))
+func hotp(key []byte, counter uint64, opts OTPOptions) (string, error) {
+	if err := opts.validate(); err != nil {
+		return "", err
+	}
+	digits := opts.digits()
+	h, _ := opts.hash()
 
-	// Signing the value using HMAC-SHA1 Algorithm
-	hash := hmac.New(sha1.New, key)
-	hash.Write(bs)
-	h := hash.Sum(nil)
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
 
-	// Grab offset
-	o := (h[19] & 15)
+	mac := hmac.New(h, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
 
-	// Get 32 bit chunk from hash starting at the o
-	var header uint32
-	r := bytes.NewReader(h[o : o+4])
-	err = binary.Read(r, binary.BigEndian, &header)
-	if err != nil {
+	// Dynamic truncation, ignoring the most significant bit
+	offset := sum[len(sum)-1] & 0xf
+	code := uint64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+
+	mod := uint64(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, code%mod), nil
+}
+
+// GenerateTOTP generates the time based one time password at t, as described
+// in RFC 6238. The secret is base32 encoded.
+func GenerateTOTP(secret string, t time.Time, opts OTPOptions) (string, error) {
+	if err := opts.validate(); err != nil {
 		return "", err
 	}
+	return GenerateHOTP(secret, totpCounter(t, opts), opts)
+}
 
-	// Ignore most significant bits as per RFC 4226, and crop to 6 digits.
-	h12 := (int(header) & 0x7fffffff) % 1000000
+// TOTPWindow generates the codes at t, and of the opts.Skew periods before
+// and after t. The code at t comes first, followed by the codes closest to t.
+func TOTPWindow(secret string, t time.Time, opts OTPOptions) ([]string, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	key, err := DecodeOTPSecret(secret)
+	if err != nil {
+		return nil, err
+	}
 
-	otp := strconv.Itoa(h12)
-	otp = prefix(otp)
+	counter := totpCounter(t, opts)
+	codes := make([]string, 0, 2*opts.Skew+1)
+	for i := 0; i <= opts.Skew; i++ {
+		for j, c := range []uint64{counter - uint64(i), counter + uint64(i)} {
+			// The current code is only added once, and there are no codes
+			// before the epoch
+			if (i == 0 && j == 1) || (j == 0 && uint64(i) > counter) {
+				continue
+			}
+			code, err := hotp(key, c, opts)
+			if err != nil {
+				return nil, err
+			}
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
 
-	return otp, nil
+// ValidateTOTP reports whether code is the code at t, or of one of the
+// opts.Skew periods before or after t.
+func ValidateTOTP(secret, code string, t time.Time, opts OTPOptions) (bool, error) {
+	codes, err := TOTPWindow(secret, t, opts)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range codes {
+		if hmac.Equal([]byte(c), []byte(code)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func totpCounter(t time.Time, opts OTPOptions) uint64 {
+	return uint64(t.Unix() / opts.period())
 }
 
-// GenTOTP will generate a one time pass based on the secret.
+// GenTOTP will generate a one time pass based on the secret, with the default
+// options.
 func GenTOTP(secret string) (string, error) {
-	//The TOTP token is just a HOTP token seeded with every 30 seconds.
-	interval := time.Now().Unix() / 30
-	otp, err := genHOTP(secret, interval)
-	return otp, err
+	return GenerateTOTP(secret, time.Now(), OTPOptions{})
+}
+
+// OTPKey is a one time password secret, with its parameters, as shared with
+// authenticator apps through otpauth:// URIs.
+type OTPKey struct {
+	// Type is either "totp" or "hotp"
+	Type string
+
+	Issuer  string
+	Account string
+
+	// Secret is base32 encoded
+	Secret string
+
+	// Counter is the initial counter of HOTP keys
+	Counter uint64
+
+	OTPOptions
+}
+
+// NewTOTPKey creates a TOTP key with the default options.
+func NewTOTPKey(issuer, account, secret string) *OTPKey {
+	return &OTPKey{Type: "totp", Issuer: issuer, Account: account, Secret: secret}
+}
+
+// ParseOTPAuthURI parses an otpauth:// URI, as encoded in the QR codes scanned
+// by authenticator apps, e.g.
+// otpauth://totp/Instagram:alice?secret=JBSWY3DPEHPK3PXP&issuer=Instagram
+func ParseOTPAuthURI(uri string) (*OTPKey, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrOTPURI, err)
+	}
+	if u.Scheme != "otpauth" {
+		return nil, fmt.Errorf("%w: scheme must be otpauth, not %q", ErrOTPURI, u.Scheme)
+	}
+
+	key := &OTPKey{Type: strings.ToLower(u.Host)}
+	if key.Type != "totp" && key.Type != "hotp" {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrOTPURI, u.Host)
+	}
+
+	label := strings.TrimPrefix(u.Path, "/")
+	if i := strings.Index(label, ":"); i != -1 {
+		key.Issuer = strings.TrimSpace(label[:i])
+		label = label[i+1:]
+	}
+	key.Account = strings.TrimSpace(label)
+
+	q := u.Query()
+	key.Secret = q.Get("secret")
+	if key.Secret == "" {
+		return nil, fmt.Errorf("%w: secret is missing", ErrOTPURI)
+	}
+	if _, err := DecodeOTPSecret(key.Secret); err != nil {
+		return nil, fmt.Errorf("%w: invalid secret: %s", ErrOTPURI, err)
+	}
+	if issuer := q.Get("issuer"); issuer != "" {
+		key.Issuer = issuer
+	}
+	if alg := q.Get("algorithm"); alg != "" {
+		key.Algorithm = OTPAlgorithm(strings.ToUpper(alg))
+		if _, err := key.hash(); err != nil {
+			return nil, err
+		}
+	}
+
+	ints := []struct {
+		param string
+		dst   *int
+	}{{"digits", &key.Digits}, {"period", &key.Period}}
+	for _, p := range ints {
+		if v := q.Get(p.param); v != "" {
+			if *p.dst, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("%w: invalid %s: %s", ErrOTPURI, p.param, err)
+			}
+		}
+	}
+	if v := q.Get("counter"); v != "" {
+		if key.Counter, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: invalid counter: %s", ErrOTPURI, err)
+		}
+	} else if key.Type == "hotp" {
+		return nil, fmt.Errorf("%w: counter is missing", ErrOTPURI)
+	}
+	if err := key.validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOTPURI, err)
+	}
+	return key, nil
+}
+
+// URI encodes the key as otpauth:// URI. Options left at their defaults are
+// omitted.
+func (k *OTPKey) URI() string {
+	label := k.Account
+	if k.Issuer != "" {
+		label = k.Issuer + ":" + label
+	}
+
+	q := url.Values{}
+	q.Set("secret", strings.TrimRight(strings.ToUpper(strings.ReplaceAll(k.Secret, " ", "")), "="))
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	if k.Algorithm != "" && k.Algorithm != AlgorithmSHA1 {
+		q.Set("algorithm", string(k.Algorithm))
+	}
+	if k.Digits != 0 && k.Digits != DefaultOTPDigits {
+		q.Set("digits", strconv.Itoa(k.Digits))
+	}
+	typ := k.Type
+	if typ == "" {
+		typ = "totp"
+	}
+	if typ == "hotp" {
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else if k.Period != 0 && k.Period != DefaultOTPPeriod {
+		q.Set("period", strconv.Itoa(k.Period))
+	}
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     typ,
+		Path:     "/" + label,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// Generate generates the code at t for TOTP keys, or the code for the initial
+// counter for HOTP keys.
+func (k *OTPKey) Generate(t time.Time) (string, error) {
+	if k.Type == "hotp" {
+		return GenerateHOTP(k.Secret, k.Counter, k.OTPOptions)
+	}
+	return GenerateTOTP(k.Secret, t, k.OTPOptions)
+}
+
+// Validate reports whether code is valid at t, within the skew of the key. For
+// HOTP keys, t is ignored, and the codes of the initial counter up to the skew
+// after it are accepted.
+func (k *OTPKey) Validate(code string, t time.Time) (bool, error) {
+	if k.Type != "hotp" {
+		return ValidateTOTP(k.Secret, code, t, k.OTPOptions)
+	}
+
+	if err := k.validate(); err != nil {
+		return false, err
+	}
+	key, err := DecodeOTPSecret(k.Secret)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i <= k.Skew; i++ {
+		c, err := hotp(key, k.Counter+uint64(i), k.OTPOptions)
+		if err != nil {
+			return false, err
+		}
+		if hmac.Equal([]byte(c), []byte(code)) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
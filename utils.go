@@ -5,9 +5,9 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"image"
 	"math"
-	"regexp"
 
 	// Required for getImageDimensionFromReader in jpg and png format
 	"fmt"
@@ -279,31 +279,9 @@ func randNum(l int) string {
 	return num
 }
 
-// checkHeadlessErr will return a proper error if a chrome browser was not found.
-func checkHeadlessErr(err error) error {
-	// Check if err = Chrome not found
-	if err != nil {
-		if matched, reErr := regexp.Match("executable file not found", []byte(err.Error())); reErr != nil {
-			return reErr
-		} else if matched {
-			return ErrChromeNotFound
-		}
-		return err
-	}
-	return nil
-}
-
 func errIsFatal(err error) bool {
-	switch err {
-	case ErrBadPassword:
-		fallthrough
-	case Err2FARequired:
-		fallthrough
-	case ErrLoggedOut:
-		fallthrough
-	case ErrLoginRequired:
-		return true
-	default:
-		return false
-	}
+	return errors.Is(err, ErrBadPassword) ||
+		errors.Is(err, Err2FARequired) ||
+		errors.Is(err, ErrLoggedOut) ||
+		errors.Is(err, ErrLoginRequired)
 }
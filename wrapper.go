@@ -1,6 +1,7 @@
 package goinsta
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -22,13 +23,17 @@ type ReqWrapperArgs struct {
 	Body    []byte
 	Headers http.Header
 	Error   error
+
+	// Set once the request has been retried
+	retried bool
 }
 
 type Wrapper struct {
-	o *ReqWrapperArgs
+	policy *RetryPolicy
 }
 
 func (w *ReqWrapperArgs) RetryRequest() (body []byte, h http.Header, err error) {
+	w.retried = true
 	return w.insta.sendRequest(w.reqOptions)
 }
 
@@ -40,6 +45,14 @@ func (w *ReqWrapperArgs) GetInsta() *Instagram {
 	return w.insta
 }
 
+// Context returns the context the request has been made with.
+func (w *ReqWrapperArgs) Context() context.Context {
+	if w.reqOptions.Context == nil {
+		return w.insta.Context()
+	}
+	return w.reqOptions.Context
+}
+
 func (w *ReqWrapperArgs) GetEndpoint() string {
 	return w.reqOptions.Endpoint
 }
@@ -53,7 +66,16 @@ func (w *ReqWrapperArgs) Ignore429() bool {
 }
 
 func DefaultWrapper() *Wrapper {
-	return &Wrapper{}
+	return &Wrapper{policy: DefaultRetryPolicy()}
+}
+
+// NewWrapper creates a default wrapper that uses the retry policy provided
+// to retry failed requests. If policy is nil, DefaultRetryPolicy is used.
+func NewWrapper(policy *RetryPolicy) *Wrapper {
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+	return &Wrapper{policy: policy}
 }
 
 // GoInstaWrapper is a warpper function for goinsta
@@ -63,32 +85,60 @@ func (w *Wrapper) GoInstaWrapper(o *ReqWrapperArgs) ([]byte, http.Header, error)
 		return o.Body, o.Headers, o.Error
 	}
 
-	// If wrapper called more than threshold, return
-	if o.GetWrapperCount() > 3 {
+	policy := w.policy
+	if policy == nil {
+		policy = DefaultRetryPolicy()
+	}
+
+	// Login again if the session expired, and auto relogin is enabled. This
+	// doesn't count towards the attempts, as the request is only sent once
+	// more.
+	if (errors.Is(o.Error, ErrLoggedOut) || errors.Is(o.Error, ErrLoginRequired)) && !o.reqOptions.Relogged {
+		insta := o.GetInsta()
+		insta.reloginMu.Lock()
+		enabled := insta.reloginPass != ""
+		insta.reloginMu.Unlock()
+
+		if enabled {
+			if err := insta.relogin(o.Context(), o.reqOptions.Authorization); err != nil {
+				return o.Body, o.Headers, fmt.Errorf("%w, %w", o.Error, err)
+			}
+			o.reqOptions.Relogged = true
+			return o.RetryRequest()
+		}
+	}
+
+	// If the max number of attempts has been reached, return
+	if o.GetWrapperCount() >= policy.MaxAttempts {
+		return o.Body, o.Headers, o.Error
+	}
+
+	// Don't retry if the request has been cancelled
+	ctx := o.Context()
+	if ctx.Err() != nil {
 		return o.Body, o.Headers, o.Error
 	}
 
-	w.o = o
 	insta := o.GetInsta()
 
 	switch true {
-	case errors.Is(o.Error, ErrTooManyRequests):
+	case errors.Is(o.Error, ErrTooManyRequests) && o.Ignore429():
 		// Some endpoints often return 429, too many requests, and can be safely ignored.
-		if o.Ignore429() {
-			return o.Body, o.Headers, nil
-		}
-			insta.warnHandler("Too many requests, sleeping for %d seconds", TooManyRequestsTimeout)
-			time.Sleep(TooManyRequestsTimeout)
+		return o.Body, o.Headers, nil
 
-	case errors.Is(o.Error, Err2FARequired):
-		// Attempt auto 2FA login with TOTP code generation
-		err := insta.TwoFactorInfo.Login2FA()
-		if err != nil && err != Err2FANoCode {
-			return o.Body, o.Headers, err
-		} else {
+	case errors.Is(o.Error, Err2FARequired) && insta.TwoFactorInfo != nil:
+		// Attempt auto 2FA login with TOTP code generation, or a code from
+		//   the code provider
+		body, err := insta.TwoFactorInfo.resolve(ctx)
+		if errors.Is(err, Err2FANoCode) {
 			return o.Body, o.Headers, o.Error
+		} else if err != nil {
+			return o.Body, o.Headers, err
 		}
 
+		// Resume the login with the response of the 2FA login
+		return body, o.Headers, nil
+
 	case errors.Is(o.Error, ErrLoggedOut):
 		fallthrough
 	case errors.Is(o.Error, ErrLoginRequired):
@@ -96,7 +146,7 @@ func (w *Wrapper) GoInstaWrapper(o *ReqWrapperArgs) ([]byte, http.Header, error)
 
 	case errors.Is(o.Error, ErrCheckpointRequired):
 		// Attempt to accecpt cookies using headless browser
-		err := insta.Checkpoint.Process()
+		err := insta.Checkpoint.ProcessContext(ctx)
 		if err != nil {
 			return o.Body, o.Headers, fmt.Errorf(
 				"failed to automatically process status code 400 'checkpoint_required' with checkpoint url '%s', please report this on github. Error provided: %w",
@@ -104,23 +154,50 @@ func (w *Wrapper) GoInstaWrapper(o *ReqWrapperArgs) ([]byte, http.Header, error)
 				err,
 			)
 		}
-		insta.infoHandler(
-			fmt.Sprintf("Auto solving of checkpoint with url '%s' seems to have gone successful. This is an experimental feature, please let me know if it works! :)\n",
-				insta.Checkpoint.URL,
-			))
+		insta.log().Info(
+			"Auto solving of checkpoint seems to have gone successful. This is an experimental feature, please let me know if it works! :)",
+			"url", insta.Checkpoint.URL,
+		)
 
 	case errors.Is(o.Error, ErrCheckpointPassed):
 		// continue without doing anything, retry request
 
 	case errors.Is(o.Error, ErrChallengeRequired):
-		if err := insta.Challenge.Process(); err != nil {
+		if err := insta.Challenge.ProcessContext(ctx); err != nil {
 			return o.Body, o.Headers, fmt.Errorf("failed to process challenge automatically with: %w", err)
 		}
+
+	case policy.retryable(o.Error, !o.reqOptions.IsPost):
+		d := policy.Delay(o.GetWrapperCount(), o.Error, o.Headers)
+		insta.log().Warn("Request failed, retrying",
+			"endpoint", o.GetEndpoint(),
+			"attempt", o.GetWrapperCount(),
+			"delay", d,
+			"error", o.Error,
+		)
+		if err := sleepContext(ctx, d); err != nil {
+			return o.Body, o.Headers, err
+		}
+
 	default:
 		// Unhandeled errors should be passed on
 		return o.Body, o.Headers, o.Error
 	}
 
-	body, h, err := w.o.RetryRequest()
+	body, h, err := o.RetryRequest()
 	return body, h, err
 }
+
+// sleepContext pauses the current goroutine for at least the duration d, or
+// until the context is cancelled, in which case the context error is returned.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}